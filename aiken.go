@@ -0,0 +1,89 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ToAiken returns the proof as an Aiken source literal of the on-chain Proof type
+func (p *Proof) ToAiken() string {
+	if len(p.steps) == 0 {
+		return "[]"
+	}
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for _, step := range p.steps {
+		sb.WriteString("  ")
+		sb.WriteString(step.ToAiken())
+		sb.WriteString(",\n")
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// ToAikenTest returns an Aiken test block which checks that the proof shows the specified
+// key and value are present in the trie with the specified root hash
+func (p *Proof) ToAikenTest(
+	name string,
+	key []byte,
+	value []byte,
+	root Hash,
+) string {
+	proofStr := strings.ReplaceAll(p.ToAiken(), "\n", "\n  ")
+	return fmt.Sprintf(
+		"test %s() {\n  let trie = mpf.from_root(#\"%s\")\n  let proof = %s\n  mpf.has(trie, #\"%x\", #\"%x\", proof)\n}\n",
+		name,
+		root.String(),
+		proofStr,
+		key,
+		value,
+	)
+}
+
+// ToAiken returns the proof step as an Aiken source literal of the on-chain ProofStep type
+func (s *ProofStep) ToAiken() string {
+	switch s.stepType {
+	case ProofStepTypeBranch:
+		var sb strings.Builder
+		for _, neighbor := range s.neighbors {
+			sb.WriteString(neighbor.String())
+		}
+		return fmt.Sprintf(
+			"Branch { skip: %d, neighbors: #\"%s\" }",
+			s.prefixLength,
+			sb.String(),
+		)
+	case ProofStepTypeFork:
+		return fmt.Sprintf(
+			"Fork { skip: %d, neighbor: Neighbor { nibble: %d, prefix: #\"%s\", root: #\"%s\" } }",
+			s.prefixLength,
+			s.neighbor.nibble,
+			hex.EncodeToString(nibblesToIndividualBytes(s.neighbor.prefix)),
+			s.neighbor.root.String(),
+		)
+	case ProofStepTypeLeaf:
+		return fmt.Sprintf(
+			"Leaf { skip: %d, key: #\"%s\", value: #\"%s\" }",
+			s.prefixLength,
+			hex.EncodeToString(nibblesToBytes(s.neighbor.key)),
+			s.neighbor.value.String(),
+		)
+	default:
+		return "unknown"
+	}
+}
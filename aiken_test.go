@@ -0,0 +1,112 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestProofToAiken(t *testing.T) {
+	neighborRoot := HashValue([]byte("neighbor"))
+	leafValue := HashValue([]byte("value"))
+	leafKey := keyToPath([]byte("key"))
+	proof := &Proof{
+		steps: []ProofStep{
+			{
+				stepType:     ProofStepTypeBranch,
+				prefixLength: 0,
+				neighbors: []Hash{
+					HashValue([]byte("a")),
+					HashValue([]byte("b")),
+					HashValue([]byte("c")),
+					NullHash,
+				},
+			},
+			{
+				stepType:     ProofStepTypeFork,
+				prefixLength: 2,
+				neighbor: ProofStepNeighbor{
+					prefix: []Nibble{0x0, 0x7, 0xa},
+					nibble: 0x3,
+					root:   neighborRoot,
+				},
+			},
+			{
+				stepType:     ProofStepTypeLeaf,
+				prefixLength: 1,
+				neighbor: ProofStepNeighbor{
+					key:   leafKey,
+					value: leafValue,
+				},
+			},
+		},
+	}
+	expected := "[\n" +
+		"  Branch { skip: 0, neighbors: #\"" +
+		HashValue([]byte("a")).String() +
+		HashValue([]byte("b")).String() +
+		HashValue([]byte("c")).String() +
+		NullHash.String() +
+		"\" },\n" +
+		"  Fork { skip: 2, neighbor: Neighbor { nibble: 3, prefix: #\"00070a\", root: #\"" +
+		neighborRoot.String() +
+		"\" } },\n" +
+		"  Leaf { skip: 1, key: #\"" +
+		hex.EncodeToString(nibblesToBytes(leafKey)) +
+		"\", value: #\"" +
+		leafValue.String() +
+		"\" },\n" +
+		"]"
+	if got := proof.ToAiken(); got != expected {
+		t.Fatalf(
+			"did not get expected Aiken literal\n  got:    %s\n  wanted: %s",
+			got,
+			expected,
+		)
+	}
+}
+
+func TestProofToAikenEmpty(t *testing.T) {
+	proof := &Proof{}
+	if got := proof.ToAiken(); got != "[]" {
+		t.Fatalf("did not get expected Aiken literal: got %s, expected []", got)
+	}
+}
+
+func TestProofToAikenTest(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	trie.Set([]byte("bcde"), []byte("2"))
+	proof, err := trie.Prove([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	expected := "test abcd_exists() {\n" +
+		"  let trie = mpf.from_root(#\"" + trie.Hash().String() + "\")\n" +
+		"  let proof = [\n" +
+		"    " + proof.steps[0].ToAiken() + ",\n" +
+		"  ]\n" +
+		"  mpf.has(trie, #\"61626364\", #\"31\", proof)\n" +
+		"}\n"
+	got := proof.ToAikenTest("abcd_exists", []byte("abcd"), []byte("1"), trie.Hash())
+	if got != expected {
+		t.Fatalf(
+			"did not get expected Aiken test\n  got:    %s\n  wanted: %s",
+			got,
+			expected,
+		)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ProofResult is the result of generating the proof for a single key with ProveAll
+type ProofResult struct {
+	Key   []byte
+	Proof *Proof
+	Err   error
+}
+
+// VerifyRequest describes a proof that the specified key and value are present in a trie,
+// to be checked with VerifyAll
+type VerifyRequest struct {
+	Key   []byte
+	Value []byte
+	Proof *Proof
+}
+
+// ProveAll generates proofs for the specified keys using the specified number of goroutines,
+// returning the results in the same order as the keys. A key that doesn't exist in the trie
+// has ErrKeyNotExist as its error. A worker count of 0 or less uses one goroutine per CPU.
+// The trie must not be modified until ProveAll returns
+func (t *Trie) ProveAll(keys [][]byte, workers int) []ProofResult {
+	ret := make([]ProofResult, len(keys))
+	parallelFor(len(keys), workers, func(idx int) {
+		proof, err := t.Prove(keys[idx])
+		ret[idx] = ProofResult{
+			Key:   keys[idx],
+			Proof: proof,
+			Err:   err,
+		}
+	})
+	return ret
+}
+
+// VerifyAll checks the specified proofs against the root hash using the specified number of
+// goroutines, returning an error for each request in the same order as the requests. The
+// error is nil for a proof that verifies, ErrProofMismatch for a proof that commits to a
+// different root, or the error encountered while computing the root from a malformed proof.
+// A worker count of 0 or less uses one goroutine per CPU
+func VerifyAll(root Hash, requests []VerifyRequest, workers int) []error {
+	ret := make([]error, len(requests))
+	parallelFor(len(requests), workers, func(idx int) {
+		req := requests[idx]
+		if req.Proof == nil {
+			ret[idx] = errors.New("missing proof")
+			return
+		}
+		tmpRoot, err := req.Proof.ComputeRoot(req.Key, req.Value)
+		if err != nil {
+			ret[idx] = err
+			return
+		}
+		if tmpRoot != root {
+			ret[idx] = ErrProofMismatch
+		}
+	})
+	return ret
+}
+
+// parallelFor calls fn for each index from 0 to count-1 using the specified number of
+// goroutines, returning once all calls have completed
+func parallelFor(count int, workers int, fn func(int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, count)
+	var nextIdx atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				idx := int(nextIdx.Add(1) - 1)
+				if idx >= count {
+					return
+				}
+				fn(idx)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTrieProveAll(t *testing.T) {
+	for _, trie := range []*Trie{NewTrie(), NewTrie(WithProofCache())} {
+		for i := range 500 {
+			trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+		}
+		// Include missing keys and repeats, which hit the proof cache when enabled
+		var keys [][]byte
+		for i := range 1200 {
+			keys = append(keys, fmt.Appendf(nil, "key-%d", i%600))
+		}
+		for _, workers := range []int{0, 1, 8} {
+			results := trie.ProveAll(keys, workers)
+			if len(results) != len(keys) {
+				t.Fatalf("did not get expected result count: got %d, expected %d", len(results), len(keys))
+			}
+			for i, result := range results {
+				if string(result.Key) != string(keys[i]) {
+					t.Fatalf("result %d is out of order: got key %q, expected %q", i, result.Key, keys[i])
+				}
+				if i%600 >= 500 {
+					if !errors.Is(result.Err, ErrKeyNotExist) {
+						t.Fatalf("did not get expected error: got %v, expected %v", result.Err, ErrKeyNotExist)
+					}
+					continue
+				}
+				if result.Err != nil {
+					t.Fatalf("got unexpected error for key %q: %s", keys[i], result.Err)
+				}
+				proof, err := trie.Prove(keys[i])
+				if err != nil {
+					t.Fatalf("got unexpected error when generating proof: %s", err)
+				}
+				assertProofStepsEqual(t, result.Proof, proof)
+			}
+		}
+	}
+}
+
+func TestVerifyAll(t *testing.T) {
+	trie := NewTrie()
+	for i := range 300 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	var keys [][]byte
+	for i := range 300 {
+		keys = append(keys, fmt.Appendf(nil, "key-%d", i))
+	}
+	results := trie.ProveAll(keys, 4)
+	var requests []VerifyRequest
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", result.Err)
+		}
+		value := fmt.Appendf(nil, "value-%d", i)
+		// Every third request has the wrong value
+		if i%3 == 2 {
+			value = []byte("wrong value")
+		}
+		requests = append(
+			requests,
+			VerifyRequest{
+				Key:   result.Key,
+				Value: value,
+				Proof: result.Proof,
+			},
+		)
+	}
+	// Add a malformed proof and a missing proof
+	forkStep, err := NewForkStep(0, keyToPath(keys[0])[0], nil, NullHash)
+	if err != nil {
+		t.Fatalf("got unexpected error when creating fork step: %s", err)
+	}
+	requests = append(
+		requests,
+		VerifyRequest{Key: keys[0], Value: []byte("value-0"), Proof: NewProof(forkStep)},
+		VerifyRequest{Key: keys[0], Value: []byte("value-0")},
+	)
+	for _, workers := range []int{0, 1, 8} {
+		errs := VerifyAll(trie.Hash(), requests, workers)
+		if len(errs) != len(requests) {
+			t.Fatalf("did not get expected result count: got %d, expected %d", len(errs), len(requests))
+		}
+		for i := range 300 {
+			if i%3 == 2 {
+				if !errors.Is(errs[i], ErrProofMismatch) {
+					t.Fatalf("did not get expected error for request %d: got %v, expected %v", i, errs[i], ErrProofMismatch)
+				}
+			} else if errs[i] != nil {
+				t.Fatalf("got unexpected error for request %d: %s", i, errs[i])
+			}
+		}
+		var malformedErr *MalformedProofError
+		if !errors.As(errs[300], &malformedErr) {
+			t.Fatalf("did not get expected MalformedProofError: got %v", errs[300])
+		}
+		if errs[301] == nil {
+			t.Fatalf("expected error for missing proof but got nil")
+		}
+	}
+}
@@ -26,6 +26,10 @@ type Branch struct {
 	prefix   []Nibble
 	children [16]Node
 	size     int
+	// count is the number of entries in the subtree under the branch
+	count int
+	// proofSteps holds the proof steps for child slots when the proof cache is enabled
+	proofSteps map[int]ProofStep
 }
 
 func newBranch(prefix []Nibble) *Branch {
@@ -62,16 +66,24 @@ func (b *Branch) Hash() Hash {
 }
 
 func (b *Branch) updateHash() {
-	tmpVal := make([]byte, 0, len(b.prefix)+HashSize)
+	// Any change to the branch invalidates its cached proof steps
+	b.proofSteps = nil
+	// Calculate merkle root for children
+	childrenHash := merkleRoot(b.children[:])
+	b.hash = branchHash(b.prefix, childrenHash)
+}
+
+// branchHash calculates the hash for a branch node with the specified prefix and merkle root of its children
+func branchHash(prefix []Nibble, childrenHash Hash) Hash {
+	tmpVal := make([]byte, 0, len(prefix)+HashSize)
 	// Append prefix
-	for _, nibble := range b.prefix {
+	for _, nibble := range prefix {
 		tmpVal = append(tmpVal, byte(nibble))
 	}
-	// Calculate merkle root for children and append
-	childrenHash := merkleRoot(b.children[:])
+	// Append children merkle root
 	tmpVal = append(tmpVal, childrenHash.Bytes()...)
 	// Calculate hash
-	b.hash = HashValue(tmpVal)
+	return HashValue(tmpVal)
 }
 
 func (b *Branch) get(path []Nibble) ([]byte, error) {
@@ -108,7 +120,9 @@ func (b *Branch) get(path []Nibble) ([]byte, error) {
 	return nil, ErrKeyNotExist
 }
 
-func (b *Branch) insert(path []Nibble, key []byte, val []byte) {
+// insert adds the key and value below the branch, returning whether a new entry was added
+// rather than the value updated for an existing key
+func (b *Branch) insert(path []Nibble, key []byte, val []byte) bool {
 	// Determine path minus the current node prefix
 	pathMinusPrefix := path[len(b.prefix):]
 	// Determine which child slot the next nibble in the path fits in
@@ -126,7 +140,7 @@ func (b *Branch) insert(path []Nibble, key []byte, val []byte) {
 				val,
 			),
 		)
-		return
+		return true
 	}
 	existingChild := b.children[childIdx]
 	switch v := existingChild.(type) {
@@ -139,7 +153,7 @@ func (b *Branch) insert(path []Nibble, key []byte, val []byte) {
 		if string(tmpPrefix) == string(v.suffix) {
 			v.Set(val)
 			b.updateHash()
-			return
+			return false
 		}
 		// Create a new branch node with the common prefix
 		tmpBranch := newBranch(tmpPrefix)
@@ -157,7 +171,9 @@ func (b *Branch) insert(path []Nibble, key []byte, val []byte) {
 		)
 		// Replace existing leaf node with new branch node
 		b.children[childIdx] = tmpBranch
+		b.count++
 		b.updateHash()
+		return true
 
 	case *Branch:
 		// Determine the common prefix nibbles between existing branch and new leaf node
@@ -165,13 +181,16 @@ func (b *Branch) insert(path []Nibble, key []byte, val []byte) {
 		// Check for common prefix matching branch prefix
 		if string(tmpPrefix) == string(v.prefix) {
 			// Insert new value in existing branch
-			v.insert(
+			added := v.insert(
 				subPath,
 				key,
 				val,
 			)
+			if added {
+				b.count++
+			}
 			b.updateHash()
-			return
+			return added
 		}
 		// Create a new branch node with the common prefix
 		tmpBranch := newBranch(tmpPrefix)
@@ -188,7 +207,9 @@ func (b *Branch) insert(path []Nibble, key []byte, val []byte) {
 		)
 		// Replace existing branch node with new branch node
 		b.children[childIdx] = tmpBranch
+		b.count++
 		b.updateHash()
+		return true
 
 	default:
 		panic(
@@ -219,6 +240,7 @@ func (b *Branch) delete(path []Nibble) error {
 		}
 		b.children[childIdx] = nil
 		b.size--
+		b.count--
 		b.updateHash()
 	case *Branch:
 		err := v.delete(
@@ -227,6 +249,7 @@ func (b *Branch) delete(path []Nibble) error {
 		if err != nil {
 			return err
 		}
+		b.count--
 		// Merge branch with only one child
 		if v.size == 1 {
 			// Find non-nil child entry
@@ -264,7 +287,7 @@ func (b *Branch) delete(path []Nibble) error {
 	return nil
 }
 
-func (b *Branch) generateProof(path []Nibble) (*Proof, error) {
+func (b *Branch) generateProof(path []Nibble, cache *proofCache) (*Proof, error) {
 	// Determine path minus the current node prefix
 	pathMinusPrefix := path[len(b.prefix):]
 	// Determine which child slot the next nibble in the path fits in
@@ -276,17 +299,86 @@ func (b *Branch) generateProof(path []Nibble) (*Proof, error) {
 		return nil, ErrKeyNotExist
 	}
 	existingChild := b.children[childIdx]
-	proof, err := existingChild.generateProof(subPath)
+	proof, err := existingChild.generateProof(subPath, cache)
 	if err != nil {
 		return nil, err
 	}
-	proof.Rewind(childIdx, len(b.prefix), b.children[:])
+	proof.steps = slices.Insert(proof.steps, 0, b.proofStep(childIdx, cache))
 	return proof, nil
 }
 
+func (b *Branch) generateExclusionProof(path []Nibble, cache *proofCache) (*Proof, error) {
+	cmnPrefix := commonPrefix(path, b.prefix)
+	if len(cmnPrefix) < len(b.prefix) {
+		// The path diverges within the branch prefix, so the key would be inserted alongside
+		// this branch in a new parent branch with the common prefix
+		proof := newProof(nil, nil)
+		proof.steps = append(
+			proof.steps,
+			ProofStep{
+				stepType:     ProofStepTypeFork,
+				prefixLength: len(cmnPrefix),
+				neighbor: ProofStepNeighbor{
+					prefix: b.prefix[len(cmnPrefix)+1:],
+					nibble: b.prefix[len(cmnPrefix)],
+					root:   merkleRoot(b.children[:]),
+				},
+			},
+		)
+		return proof, nil
+	}
+	// Determine path minus the current node prefix
+	pathMinusPrefix := path[len(b.prefix):]
+	// Determine which child slot the next nibble in the path fits in
+	childIdx := int(pathMinusPrefix[0])
+	// Determine sub-path for key. We strip off the first nibble, since it's implied by
+	// the child slot that it's in
+	subPath := pathMinusPrefix[1:]
+	proof := newProof(nil, nil)
+	if b.children[childIdx] != nil {
+		var err error
+		proof, err = b.children[childIdx].generateExclusionProof(subPath, cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	proof.steps = slices.Insert(proof.steps, 0, b.proofStep(childIdx, cache))
+	return proof, nil
+}
+
+func (b *Branch) generateSubtreeProof(path []Nibble) (*SubtreeProof, error) {
+	cmnPrefix := commonPrefix(path, b.prefix)
+	if len(cmnPrefix) == len(path) {
+		// The path ends within the branch prefix, so this branch covers it
+		return &SubtreeProof{
+			proof:  newProof(nil, nil),
+			prefix: slices.Clone(b.prefix),
+			root:   merkleRoot(b.children[:]),
+		}, nil
+	}
+	if len(cmnPrefix) < len(b.prefix) {
+		return nil, ErrPrefixNotExist
+	}
+	// Determine path minus the current node prefix
+	pathMinusPrefix := path[len(b.prefix):]
+	// Determine which child slot the next nibble in the path fits in
+	childIdx := int(pathMinusPrefix[0])
+	// Determine sub-path for the prefix. We strip off the first nibble, since it's implied by
+	// the child slot that it's in
+	subPath := pathMinusPrefix[1:]
+	ret, err := generateSubtreeProof(b.children[childIdx], subPath)
+	if err != nil {
+		return nil, err
+	}
+	ret.proof.Rewind(childIdx, len(b.prefix), b.children[:])
+	ret.prefix = slices.Concat(b.prefix, []Nibble{Nibble(childIdx)}, ret.prefix)
+	return ret, nil
+}
+
 func (b *Branch) addChild(slot int, child Node) {
 	empty := b.children[slot] == nil
 
+	b.count += nodeCount(child) - nodeCount(b.children[slot])
 	b.children[slot] = child
 	// Increment the child node count
 	if empty {
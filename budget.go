@@ -0,0 +1,546 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ProofOperation identifies the on-chain function that a proof is checked with
+type ProofOperation int
+
+const (
+	ProofOperationIncluding ProofOperation = 1
+	ProofOperationExcluding ProofOperation = 2
+	ProofOperationInsert    ProofOperation = 3
+	ProofOperationDelete    ProofOperation = 4
+	ProofOperationUpdate    ProofOperation = 5
+)
+
+func (o ProofOperation) String() string {
+	switch o {
+	case ProofOperationIncluding:
+		return "including"
+	case ProofOperationExcluding:
+		return "excluding"
+	case ProofOperationInsert:
+		return "insert"
+	case ProofOperationDelete:
+		return "delete"
+	case ProofOperationUpdate:
+		return "update"
+	default:
+		return "unknown"
+	}
+}
+
+// ExUnits is a Plutus execution budget
+type ExUnits struct {
+	Mem   int64
+	Steps int64
+}
+
+// Add returns the sum of both budgets
+func (e ExUnits) Add(other ExUnits) ExUnits {
+	return ExUnits{
+		Mem:   e.Mem + other.Mem,
+		Steps: e.Steps + other.Steps,
+	}
+}
+
+// Fits returns whether the budget is within the specified limit
+func (e ExUnits) Fits(limit ExUnits) bool {
+	return e.Mem <= limit.Mem && e.Steps <= limit.Steps
+}
+
+// Builtin identifies a Plutus builtin function used by the on-chain proof verification
+type Builtin int
+
+const (
+	BuiltinBlake2b256       Builtin = 1
+	BuiltinAppendByteString Builtin = 2
+	BuiltinConsByteString   Builtin = 3
+	BuiltinSliceByteString  Builtin = 4
+	BuiltinIndexByteString  Builtin = 5
+)
+
+func (b Builtin) String() string {
+	switch b {
+	case BuiltinBlake2b256:
+		return "blake2b_256"
+	case BuiltinAppendByteString:
+		return "appendByteString"
+	case BuiltinConsByteString:
+		return "consByteString"
+	case BuiltinSliceByteString:
+		return "sliceByteString"
+	case BuiltinIndexByteString:
+		return "indexByteString"
+	default:
+		return "unknown"
+	}
+}
+
+// LinearCost is a cost of the form Intercept + Slope*size, where size is the total size of
+// the byte string arguments in 8-byte words, as in the Plutus cost model
+type LinearCost struct {
+	Intercept int64
+	Slope     int64
+}
+
+func (c LinearCost) cost(size int64) int64 {
+	return c.Intercept + c.Slope*size
+}
+
+// BuiltinCost is the CPU and memory cost of a Plutus builtin function
+type BuiltinCost struct {
+	CPU LinearCost
+	Mem LinearCost
+}
+
+// CostModel estimates the execution budget used by the Aiken merkle-patricia-forestry
+// library to check a proof. The builtin costs come from the protocol parameters, while the
+// machine step counts approximate the overhead of evaluating the compiled validator code.
+// The estimate is increased by MarginPercent to allow for error in the machine step counts
+type CostModel struct {
+	// Builtins holds the cost of each builtin used during verification
+	Builtins map[Builtin]BuiltinCost
+	// MachineStep is the cost of a single evaluator step
+	MachineStep ExUnits
+	// BaseMachineSteps is the number of evaluator steps for each call to including or excluding
+	BaseMachineSteps int64
+	// StepMachineSteps is the number of evaluator steps for each proof step type
+	StepMachineSteps map[ProofStepType]int64
+	// NibbleMachineSteps is the number of evaluator steps for each prefix nibble converted
+	// to bytes
+	NibbleMachineSteps int64
+	// MarginPercent is the percentage added to the estimated memory and CPU, rounded up
+	MarginPercent int64
+}
+
+// DefaultCostModelMarginPercent is the safety margin used by DefaultCostModel. The machine
+// step counts in the default model are not calibrated against budgets from evaluating the
+// Aiken library, so the margin is large enough that an estimate errs toward a budget that is
+// too high, which only costs extra fees, rather than one that is too low, which fails the
+// transaction on chain. Calibrate replaces it with the margin that the measured budgets need
+const DefaultCostModelMarginPercent = 100
+
+// DefaultCostModel returns a cost model with builtin costs based on the mainnet Plutus V3
+// cost model and rough machine step counts for the Aiken library, with a margin of
+// DefaultCostModelMarginPercent. Where accuracy matters, use Calibrate with budgets from
+// evaluating the validator for a few proofs
+func DefaultCostModel() *CostModel {
+	return &CostModel{
+		Builtins: map[Builtin]BuiltinCost{
+			BuiltinBlake2b256: {
+				CPU: LinearCost{Intercept: 117366, Slope: 10475},
+				Mem: LinearCost{Intercept: 4},
+			},
+			BuiltinAppendByteString: {
+				CPU: LinearCost{Intercept: 1000, Slope: 571},
+				Mem: LinearCost{Slope: 1},
+			},
+			BuiltinConsByteString: {
+				CPU: LinearCost{Intercept: 72010, Slope: 178},
+				Mem: LinearCost{Slope: 1},
+			},
+			BuiltinSliceByteString: {
+				CPU: LinearCost{Intercept: 20467, Slope: 1},
+				Mem: LinearCost{Intercept: 4},
+			},
+			BuiltinIndexByteString: {
+				CPU: LinearCost{Intercept: 57667},
+				Mem: LinearCost{Intercept: 4},
+			},
+		},
+		MachineStep:      ExUnits{Mem: 100, Steps: 16000},
+		BaseMachineSteps: 60,
+		StepMachineSteps: map[ProofStepType]int64{
+			ProofStepTypeBranch: 120,
+			ProofStepTypeFork:   150,
+			ProofStepTypeLeaf:   150,
+		},
+		NibbleMachineSteps: 15,
+		MarginPercent:      DefaultCostModelMarginPercent,
+	}
+}
+
+// Estimate returns the estimated execution budget for checking the proof with the specified
+// operation for the key and value. For an update, the old and new values are assumed to be
+// the same size
+func (m *CostModel) Estimate(
+	proof *Proof,
+	op ProofOperation,
+	key []byte,
+	value []byte,
+) (ExUnits, error) {
+	e, err := m.estimate(proof, op, key, value)
+	if err != nil {
+		return ExUnits{}, err
+	}
+	if m.MarginPercent < 0 {
+		return ExUnits{}, fmt.Errorf("invalid margin: %d", m.MarginPercent)
+	}
+	total := e.total()
+	return ExUnits{
+		Mem:   addMargin(total.Mem, m.MarginPercent),
+		Steps: addMargin(total.Steps, m.MarginPercent),
+	}, nil
+}
+
+// estimate returns the cost of checking the proof before the margin is added
+func (m *CostModel) estimate(
+	proof *Proof,
+	op ProofOperation,
+	key []byte,
+	value []byte,
+) (*costEstimate, error) {
+	if proof == nil {
+		return nil, errors.New("missing proof")
+	}
+	path := keyToPath(key)
+	cursors, err := proof.stepCursors(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &costEstimate{
+		model: m,
+		counts: costCounts{
+			steps: make(map[ProofStepType]int64),
+		},
+	}
+	e.blake2b(len(key))
+	switch op {
+	case ProofOperationIncluding:
+		e.blake2b(len(value))
+		e.including(proof, path, cursors)
+	case ProofOperationExcluding:
+		e.excluding(proof, path, cursors)
+	case ProofOperationInsert, ProofOperationDelete:
+		e.blake2b(len(value))
+		e.excluding(proof, path, cursors)
+		e.including(proof, path, cursors)
+	case ProofOperationUpdate:
+		e.blake2b(len(value))
+		e.blake2b(len(value))
+		e.including(proof, path, cursors)
+		e.including(proof, path, cursors)
+	default:
+		return nil, fmt.Errorf("unknown proof operation: %d", op)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// CostSample is the execution budget measured for checking a proof with an operation, such
+// as from aiken check or a transaction evaluator
+type CostSample struct {
+	Proof     *Proof
+	Operation ProofOperation
+	Key       []byte
+	Value     []byte
+	Measured  ExUnits
+}
+
+// calibrationParams is the number of machine step counts set by Calibrate
+const calibrationParams = 5
+
+// Calibrate sets the machine step counts to the best fit for the measured budgets, keeping
+// the builtin costs and the cost of a machine step. MarginPercent is then set to the smallest
+// margin for which the estimate covers every measured budget. The samples must include each
+// proof step type and proofs with and without branch prefixes
+func (m *CostModel) Calibrate(samples []CostSample) error {
+	if m.MachineStep.Steps <= 0 {
+		return fmt.Errorf("invalid machine step cost: %d", m.MachineStep.Steps)
+	}
+	// Fit the CPU left over after the builtins, in machine steps, by least squares
+	var normal [calibrationParams][calibrationParams + 1]float64
+	estimates := make([]*costEstimate, 0, len(samples))
+	for i, sample := range samples {
+		e, err := m.estimate(sample.Proof, sample.Operation, sample.Key, sample.Value)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i, err)
+		}
+		estimates = append(estimates, e)
+		row := [calibrationParams]float64{
+			float64(e.counts.base),
+			float64(e.counts.steps[ProofStepTypeBranch]),
+			float64(e.counts.steps[ProofStepTypeFork]),
+			float64(e.counts.steps[ProofStepTypeLeaf]),
+			float64(e.counts.nibbles),
+		}
+		residual := float64(sample.Measured.Steps-e.builtins.Steps) /
+			float64(m.MachineStep.Steps)
+		for j := range calibrationParams {
+			for k := range calibrationParams {
+				normal[j][k] += row[j] * row[k]
+			}
+			normal[j][calibrationParams] += row[j] * residual
+		}
+	}
+	params, err := solveLinear(normal)
+	if err != nil {
+		return fmt.Errorf("samples do not determine the machine step counts: %w", err)
+	}
+	counts := make([]int64, 0, calibrationParams)
+	for _, param := range params {
+		counts = append(counts, max(0, int64(math.Round(param))))
+	}
+	m.BaseMachineSteps = counts[0]
+	m.StepMachineSteps = map[ProofStepType]int64{
+		ProofStepTypeBranch: counts[1],
+		ProofStepTypeFork:   counts[2],
+		ProofStepTypeLeaf:   counts[3],
+	}
+	m.NibbleMachineSteps = counts[4]
+	// Find the margin needed to cover every sample
+	m.MarginPercent = 0
+	for i, e := range estimates {
+		total := e.total()
+		m.MarginPercent = max(
+			m.MarginPercent,
+			neededMargin(total.Mem, samples[i].Measured.Mem),
+			neededMargin(total.Steps, samples[i].Measured.Steps),
+		)
+	}
+	return nil
+}
+
+// neededMargin returns the smallest percentage which increases the estimate to at least the
+// measured value
+func neededMargin(estimate int64, measured int64) int64 {
+	if estimate <= 0 || measured <= estimate {
+		return 0
+	}
+	ret := (measured - estimate) * 100 / estimate
+	for addMargin(estimate, ret) < measured {
+		ret++
+	}
+	return ret
+}
+
+// solveLinear solves the system of linear equations with the specified augmented matrix by
+// Gaussian elimination with partial pivoting
+func solveLinear(matrix [calibrationParams][calibrationParams + 1]float64) ([]float64, error) {
+	for col := range calibrationParams {
+		pivot := col
+		for row := col + 1; row < calibrationParams; row++ {
+			if math.Abs(matrix[row][col]) > math.Abs(matrix[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(matrix[pivot][col]) < 1e-9 {
+			return nil, errors.New("singular system")
+		}
+		matrix[col], matrix[pivot] = matrix[pivot], matrix[col]
+		for row := range calibrationParams {
+			if row == col {
+				continue
+			}
+			factor := matrix[row][col] / matrix[col][col]
+			for k := col; k <= calibrationParams; k++ {
+				matrix[row][k] -= factor * matrix[col][k]
+			}
+		}
+	}
+	ret := make([]float64, 0, calibrationParams)
+	for row := range calibrationParams {
+		ret = append(ret, matrix[row][calibrationParams]/matrix[row][row])
+	}
+	return ret, nil
+}
+
+// addMargin returns the value increased by the specified percentage, rounded up
+func addMargin(value int64, percent int64) int64 {
+	return value + (value*percent+99)/100
+}
+
+// costCounts is the number of times each part of the on-chain verification that is charged
+// in machine steps is run
+type costCounts struct {
+	// base is the number of calls to including or excluding
+	base int64
+	// steps is the number of proof steps of each type that are walked
+	steps map[ProofStepType]int64
+	// nibbles is the number of prefix nibbles converted to bytes
+	nibbles int64
+}
+
+// costEstimate accumulates the cost of the on-chain verification steps
+type costEstimate struct {
+	model    *CostModel
+	builtins ExUnits
+	counts   costCounts
+	err      error
+}
+
+// total returns the cost of the builtins along with the machine steps
+func (e *costEstimate) total() ExUnits {
+	count := e.counts.base*e.model.BaseMachineSteps +
+		e.counts.nibbles*e.model.NibbleMachineSteps
+	for stepType, stepCount := range e.counts.steps {
+		count += stepCount * e.model.StepMachineSteps[stepType]
+	}
+	return e.builtins.Add(
+		ExUnits{
+			Mem:   e.model.MachineStep.Mem * count,
+			Steps: e.model.MachineStep.Steps * count,
+		},
+	)
+}
+
+// byteStringSize returns the size of a byte string in 8-byte words, as in the Plutus cost
+// model
+func byteStringSize(length int) int64 {
+	return int64((length-1)/8 + 1)
+}
+
+func (e *costEstimate) builtin(builtin Builtin, size int64) {
+	cost, ok := e.model.Builtins[builtin]
+	if !ok {
+		if e.err == nil {
+			e.err = fmt.Errorf("missing cost for builtin: %s", builtin)
+		}
+		return
+	}
+	e.builtins = e.builtins.Add(
+		ExUnits{
+			Mem:   cost.Mem.cost(size),
+			Steps: cost.CPU.cost(size),
+		},
+	)
+}
+
+func (e *costEstimate) blake2b(length int) {
+	e.builtin(BuiltinBlake2b256, byteStringSize(length))
+}
+
+// combine is the cost of hashing two byte strings together
+func (e *costEstimate) combine(lengthA int, lengthB int) {
+	e.builtin(BuiltinAppendByteString, byteStringSize(lengthA)+byteStringSize(lengthB))
+	e.blake2b(lengthA + lengthB)
+}
+
+// nibbles is the cost of converting part of a path into one byte per nibble
+func (e *costEstimate) nibbles(count int) {
+	for i := range count {
+		e.builtin(BuiltinIndexByteString, byteStringSize(HashSize))
+		e.builtin(BuiltinConsByteString, byteStringSize(i))
+	}
+	e.counts.nibbles += int64(count)
+}
+
+// suffix is the cost of encoding the remainder of a path from the specified position, and
+// returns the length of the result
+func (e *costEstimate) suffix(cursor int) int {
+	remaining := (HashSize*2 - cursor + 1) / 2
+	e.builtin(BuiltinSliceByteString, byteStringSize(HashSize))
+	e.builtin(BuiltinConsByteString, byteStringSize(remaining))
+	if cursor%2 == 1 {
+		e.builtin(BuiltinIndexByteString, byteStringSize(HashSize))
+		e.builtin(BuiltinConsByteString, byteStringSize(remaining+1))
+		return remaining + 2
+	}
+	return remaining + 1
+}
+
+// sparseMerkle is the cost of the merkle root of a branch with two children in the
+// specified slots
+func (e *costEstimate) sparseMerkle(idxA Nibble, idxB Nibble) {
+	level := 0
+	for level < branchProofNeighborCount-1 && (idxA^idxB)&(8>>level) == 0 {
+		level++
+	}
+	for range 7 - level {
+		e.combine(HashSize, HashSize)
+	}
+}
+
+func (e *costEstimate) including(proof *Proof, path []Nibble, cursors []int) {
+	e.counts.base++
+	e.combine(e.suffix(cursors[len(proof.steps)]), HashSize)
+	for i := len(proof.steps) - 1; i >= 0; i-- {
+		e.step(&proof.steps[i], path, cursors[i])
+	}
+}
+
+func (e *costEstimate) excluding(proof *Proof, path []Nibble, cursors []int) {
+	e.counts.base++
+	if len(proof.steps) == 0 {
+		return
+	}
+	lastIdx := len(proof.steps) - 1
+	lastStep := &proof.steps[lastIdx]
+	switch lastStep.stepType {
+	case ProofStepTypeBranch:
+		e.step(lastStep, path, cursors[lastIdx])
+	case ProofStepTypeFork:
+		// The neighbor absorbs the branch prefix and its slot nibble
+		e.counts.steps[lastStep.stepType]++
+		e.nibbles(lastStep.prefixLength)
+		prefixLength := lastStep.prefixLength + 1 + len(lastStep.neighbor.prefix)
+		e.builtin(
+			BuiltinConsByteString,
+			byteStringSize(lastStep.prefixLength),
+		)
+		e.builtin(
+			BuiltinAppendByteString,
+			byteStringSize(lastStep.prefixLength+1)+byteStringSize(len(lastStep.neighbor.prefix)),
+		)
+		e.combine(prefixLength, HashSize)
+	case ProofStepTypeLeaf:
+		// The neighbor leaf moves up to the branch position
+		e.counts.steps[lastStep.stepType]++
+		e.combine(e.suffix(cursors[lastIdx]), HashSize)
+	default:
+		e.err = errors.New("unknown proof step type")
+	}
+	for i := lastIdx - 1; i >= 0; i-- {
+		e.step(&proof.steps[i], path, cursors[i])
+	}
+}
+
+// step is the cost of computing the hash of the branch described by a proof step from the
+// hash of the child on the path
+func (e *costEstimate) step(step *ProofStep, path []Nibble, cursor int) {
+	e.counts.steps[step.stepType]++
+	nextCursor := cursor + 1 + step.prefixLength
+	childIdx := path[nextCursor-1]
+	switch step.stepType {
+	case ProofStepTypeBranch:
+		for range branchProofNeighborCount {
+			e.combine(HashSize, HashSize)
+		}
+	case ProofStepTypeFork:
+		e.combine(len(step.neighbor.prefix), HashSize)
+		e.sparseMerkle(childIdx, step.neighbor.nibble)
+	case ProofStepTypeLeaf:
+		if len(step.neighbor.key) < nextCursor {
+			e.err = errors.New("leaf neighbor key is too short")
+			return
+		}
+		e.combine(e.suffix(nextCursor), HashSize)
+		e.sparseMerkle(childIdx, step.neighbor.key[nextCursor-1])
+	default:
+		e.err = errors.New("unknown proof step type")
+		return
+	}
+	e.nibbles(step.prefixLength)
+	e.combine(step.prefixLength, HashSize)
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"testing"
+)
+
+func TestCostModelEstimate(t *testing.T) {
+	model := DefaultCostModel()
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	for _, entry := range fruitsTestEntries {
+		key := []byte(entry.key)
+		value := []byte(entry.value)
+		proof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		estimates := make(map[ProofOperation]ExUnits)
+		for _, op := range []ProofOperation{
+			ProofOperationIncluding,
+			ProofOperationExcluding,
+			ProofOperationInsert,
+			ProofOperationDelete,
+			ProofOperationUpdate,
+		} {
+			estimate, err := model.Estimate(proof, op, key, value)
+			if err != nil {
+				t.Fatalf("got unexpected error when estimating %s: %s", op, err)
+			}
+			if estimate.Mem <= 0 || estimate.Steps <= 0 {
+				t.Fatalf("did not get positive estimate for %s: %+v", op, estimate)
+			}
+			estimates[op] = estimate
+		}
+		// Insert checks both with and without the key
+		if estimates[ProofOperationInsert].Steps <= estimates[ProofOperationIncluding].Steps ||
+			estimates[ProofOperationInsert].Steps <= estimates[ProofOperationExcluding].Steps {
+			t.Fatalf("insert estimate is not larger than its parts: %+v", estimates)
+		}
+		if estimates[ProofOperationInsert] != estimates[ProofOperationDelete] {
+			t.Fatalf("insert and delete estimates differ: %+v", estimates)
+		}
+		if !estimates[ProofOperationIncluding].Fits(estimates[ProofOperationUpdate]) {
+			t.Fatalf("including estimate does not fit within update estimate: %+v", estimates)
+		}
+	}
+}
+
+func TestCostModelEstimateGrowsWithProof(t *testing.T) {
+	model := DefaultCostModel()
+	key := []byte("key")
+	value := []byte("value")
+	// More branch steps
+	var steps []ProofStep
+	var lastEstimate ExUnits
+	for range 4 {
+		step, err := NewBranchStep(0, make([]Hash, branchProofNeighborCount))
+		if err != nil {
+			t.Fatalf("got unexpected error when building proof step: %s", err)
+		}
+		steps = append(steps, step)
+		estimate, err := model.Estimate(NewProof(steps...), ProofOperationIncluding, key, value)
+		if err != nil {
+			t.Fatalf("got unexpected error when estimating: %s", err)
+		}
+		if estimate.Steps <= lastEstimate.Steps || estimate.Mem <= lastEstimate.Mem {
+			t.Fatalf(
+				"estimate did not grow with %d steps: got %+v, previous %+v",
+				len(steps),
+				estimate,
+				lastEstimate,
+			)
+		}
+		lastEstimate = estimate
+	}
+	// Longer fork neighbor prefixes
+	path := keyToPath(key)
+	lastEstimate = ExUnits{}
+	for _, prefixLength := range []int{0, 8, 32} {
+		step, err := NewForkStep(0, path[0]^1, make([]Nibble, prefixLength), NullHash)
+		if err != nil {
+			t.Fatalf("got unexpected error when building proof step: %s", err)
+		}
+		estimate, err := model.Estimate(NewProof(step), ProofOperationIncluding, key, value)
+		if err != nil {
+			t.Fatalf("got unexpected error when estimating: %s", err)
+		}
+		if estimate.Steps <= lastEstimate.Steps {
+			t.Fatalf(
+				"estimate did not grow with fork prefix length %d: got %+v, previous %+v",
+				prefixLength,
+				estimate,
+				lastEstimate,
+			)
+		}
+		lastEstimate = estimate
+	}
+}
+
+func TestCostModelCustomBuiltins(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	key := []byte(fruitsTestEntries[0].key)
+	value := []byte(fruitsTestEntries[0].value)
+	proof, err := trie.Prove(key)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	model := DefaultCostModel()
+	baseEstimate, err := model.Estimate(proof, ProofOperationIncluding, key, value)
+	if err != nil {
+		t.Fatalf("got unexpected error when estimating: %s", err)
+	}
+	blake2bCost := model.Builtins[BuiltinBlake2b256]
+	blake2bCost.CPU.Intercept *= 2
+	model.Builtins[BuiltinBlake2b256] = blake2bCost
+	estimate, err := model.Estimate(proof, ProofOperationIncluding, key, value)
+	if err != nil {
+		t.Fatalf("got unexpected error when estimating: %s", err)
+	}
+	if estimate.Steps <= baseEstimate.Steps || estimate.Mem != baseEstimate.Mem {
+		t.Fatalf(
+			"did not get expected estimate change: got %+v, base %+v",
+			estimate,
+			baseEstimate,
+		)
+	}
+	delete(model.Builtins, BuiltinBlake2b256)
+	if _, err := model.Estimate(proof, ProofOperationIncluding, key, value); err == nil {
+		t.Fatal("expected missing builtin error but got nil")
+	}
+}
+
+func TestCostModelMargin(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	key := []byte(fruitsTestEntries[0].key)
+	value := []byte(fruitsTestEntries[0].value)
+	proof, err := trie.Prove(key)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	model := DefaultCostModel()
+	if model.MarginPercent != DefaultCostModelMarginPercent {
+		t.Fatalf(
+			"did not get expected default margin: got %d, expected %d",
+			model.MarginPercent,
+			DefaultCostModelMarginPercent,
+		)
+	}
+	defaultEstimate, err := model.Estimate(proof, ProofOperationIncluding, key, value)
+	if err != nil {
+		t.Fatalf("got unexpected error when estimating: %s", err)
+	}
+	model.MarginPercent = 0
+	baseEstimate, err := model.Estimate(proof, ProofOperationIncluding, key, value)
+	if err != nil {
+		t.Fatalf("got unexpected error when estimating: %s", err)
+	}
+	expected := ExUnits{
+		Mem:   baseEstimate.Mem + (baseEstimate.Mem*DefaultCostModelMarginPercent+99)/100,
+		Steps: baseEstimate.Steps + (baseEstimate.Steps*DefaultCostModelMarginPercent+99)/100,
+	}
+	if defaultEstimate != expected {
+		t.Fatalf("did not get expected estimate with margin: got %+v, expected %+v", defaultEstimate, expected)
+	}
+	model.MarginPercent = -1
+	if _, err := model.Estimate(proof, ProofOperationIncluding, key, value); err == nil {
+		t.Fatal("expected invalid margin error but got nil")
+	}
+}
+
+// costCalibrationProofs returns proofs covering each step type, with and without branch
+// prefixes, along with the keys and values they're for
+func costCalibrationProofs(t *testing.T) ([]*Proof, [][]byte, [][]byte) {
+	t.Helper()
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	var proofs []*Proof
+	var keys, values [][]byte
+	for _, entry := range fruitsTestEntries {
+		proof, err := trie.Prove([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		proofs = append(proofs, proof)
+		keys = append(keys, []byte(entry.key))
+		values = append(values, []byte(entry.value))
+	}
+	key := []byte("key")
+	path := keyToPath(key)
+	for _, prefixLength := range []int{0, 3, 8} {
+		branchStep, err := NewBranchStep(prefixLength, make([]Hash, branchProofNeighborCount))
+		if err != nil {
+			t.Fatalf("got unexpected error when building proof step: %s", err)
+		}
+		forkStep, err := NewForkStep(prefixLength, path[prefixLength]^1, make([]Nibble, 20), NullHash)
+		if err != nil {
+			t.Fatalf("got unexpected error when building proof step: %s", err)
+		}
+		proofs = append(proofs, NewProof(branchStep), NewProof(forkStep))
+		keys = append(keys, key, key)
+		values = append(values, []byte("value"), []byte("value"))
+	}
+	return proofs, keys, values
+}
+
+func TestCostModelCalibrate(t *testing.T) {
+	// Budgets from a model with different machine step counts should be matched exactly
+	measuredModel := DefaultCostModel()
+	measuredModel.BaseMachineSteps = 75
+	measuredModel.StepMachineSteps = map[ProofStepType]int64{
+		ProofStepTypeBranch: 140,
+		ProofStepTypeFork:   170,
+		ProofStepTypeLeaf:   160,
+	}
+	measuredModel.NibbleMachineSteps = 12
+	measuredModel.MarginPercent = 0
+	proofs, keys, values := costCalibrationProofs(t)
+	var samples []CostSample
+	for i, proof := range proofs {
+		for _, op := range []ProofOperation{
+			ProofOperationIncluding,
+			ProofOperationExcluding,
+			ProofOperationInsert,
+			ProofOperationUpdate,
+		} {
+			measured, err := measuredModel.Estimate(proof, op, keys[i], values[i])
+			if err != nil {
+				t.Fatalf("got unexpected error when estimating: %s", err)
+			}
+			samples = append(samples, CostSample{
+				Proof:     proof,
+				Operation: op,
+				Key:       keys[i],
+				Value:     values[i],
+				Measured:  measured,
+			})
+		}
+	}
+	model := DefaultCostModel()
+	if err := model.Calibrate(samples); err != nil {
+		t.Fatalf("got unexpected error when calibrating: %s", err)
+	}
+	if model.BaseMachineSteps != measuredModel.BaseMachineSteps ||
+		model.NibbleMachineSteps != measuredModel.NibbleMachineSteps ||
+		model.MarginPercent != 0 {
+		t.Fatalf("did not get expected calibrated model: got %+v, expected %+v", model, measuredModel)
+	}
+	for stepType, count := range measuredModel.StepMachineSteps {
+		if model.StepMachineSteps[stepType] != count {
+			t.Fatalf(
+				"did not get expected %s machine steps: got %d, expected %d",
+				stepType,
+				model.StepMachineSteps[stepType],
+				count,
+			)
+		}
+	}
+	// A budget above the fit raises the margin just enough to cover it
+	samples[0].Measured.Steps = samples[0].Measured.Steps * 103 / 100
+	if err := model.Calibrate(samples); err != nil {
+		t.Fatalf("got unexpected error when calibrating: %s", err)
+	}
+	if model.MarginPercent < 1 || model.MarginPercent > 5 {
+		t.Fatalf("did not get expected margin: got %d", model.MarginPercent)
+	}
+	for i, sample := range samples {
+		estimate, err := model.Estimate(sample.Proof, sample.Operation, sample.Key, sample.Value)
+		if err != nil {
+			t.Fatalf("got unexpected error when estimating: %s", err)
+		}
+		if !sample.Measured.Fits(estimate) {
+			t.Fatalf("sample %d: estimate %+v does not cover measured budget %+v", i, estimate, sample.Measured)
+		}
+	}
+	// Samples with a single kind of proof can't tell the step counts apart
+	if err := DefaultCostModel().Calibrate(samples[:1]); err == nil {
+		t.Fatal("expected calibration error but got nil")
+	}
+}
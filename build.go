@@ -0,0 +1,122 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"iter"
+	"slices"
+)
+
+// buildEntry is a key and value along with the hash path of the key, used when building a
+// trie in bulk
+type buildEntry struct {
+	path  []Nibble
+	key   []byte
+	value []byte
+}
+
+// FromList returns a trie containing the specified entries. The trie is built bottom-up
+// from the entries sorted by hash path, which hashes each node once rather than once for
+// every entry inserted below it. The result is the same as calling Set for each entry in
+// order, so a later entry replaces an earlier entry with the same key. The keys and values
+// are copied into the trie, so the entries may be modified once FromList returns, but not
+// while it runs
+func FromList(entries []Entry, opts ...TrieOption) *Trie {
+	buildEntries := make([]buildEntry, 0, len(entries))
+	for _, entry := range entries {
+		buildEntries = append(
+			buildEntries,
+			buildEntry{
+				path:  keyToPath(entry.Key),
+				key:   entry.Key,
+				value: entry.Value,
+			},
+		)
+	}
+	return buildTrie(buildEntries, opts...)
+}
+
+// FromSeq returns a trie containing the keys and values from the iterator, as with FromList.
+// Each key and value is copied as it's yielded, so the iterator may reuse its buffers
+func FromSeq(seq iter.Seq2[[]byte, []byte], opts ...TrieOption) *Trie {
+	var buildEntries []buildEntry
+	for key, value := range seq {
+		buildEntries = append(
+			buildEntries,
+			buildEntry{
+				path:  keyToPath(key),
+				key:   bytes.Clone(key),
+				value: bytes.Clone(value),
+			},
+		)
+	}
+	return buildTrie(buildEntries, opts...)
+}
+
+func buildTrie(entries []buildEntry, opts ...TrieOption) *Trie {
+	t := NewTrie(opts...)
+	// Sort by path, keeping the original order for duplicate keys so that the last one wins
+	slices.SortStableFunc(entries, func(a, b buildEntry) int {
+		return slices.Compare(a.path, b.path)
+	})
+	uniqEntries := entries[:0]
+	for _, entry := range entries {
+		if len(uniqEntries) > 0 && slices.Equal(uniqEntries[len(uniqEntries)-1].path, entry.path) {
+			uniqEntries[len(uniqEntries)-1] = entry
+			continue
+		}
+		uniqEntries = append(uniqEntries, entry)
+	}
+	if len(uniqEntries) == 0 {
+		return t
+	}
+	t.rootNode = buildNode(uniqEntries, 0)
+	t.size = len(uniqEntries)
+	return t
+}
+
+// buildNode returns the node for the sorted entries, which all share the same path up to
+// the specified depth
+func buildNode(entries []buildEntry, depth int) Node {
+	if len(entries) == 1 {
+		return newLeaf(
+			entries[0].path[depth:],
+			entries[0].key,
+			entries[0].value,
+		)
+	}
+	// Since the entries are sorted, the prefix common to the first and last entries is
+	// common to all of them
+	first := entries[0].path[depth:]
+	last := entries[len(entries)-1].path[depth:]
+	prefix := commonPrefix(first, last)
+	b := newBranch(prefix)
+	childDepth := depth + len(prefix)
+	// Group entries by the nibble following the prefix, building each child slot
+	for start := 0; start < len(entries); {
+		childIdx := entries[start].path[childDepth]
+		end := start + 1
+		for end < len(entries) && entries[end].path[childDepth] == childIdx {
+			end++
+		}
+		b.children[childIdx] = buildNode(entries[start:end], childDepth+1)
+		b.size++
+		b.count += end - start
+		start = end
+	}
+	b.updateHash()
+	return b
+}
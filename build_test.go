@@ -0,0 +1,134 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestFromList(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, count := range []int{0, 1, 2, 17, 1000} {
+		var entries []Entry
+		incTrie := NewTrie()
+		for i := range count {
+			entry := Entry{
+				Key:   fmt.Appendf(nil, "key-%d", rng.Intn(count+count/4)),
+				Value: fmt.Appendf(nil, "value-%d", i),
+			}
+			entries = append(entries, entry)
+			incTrie.Set(entry.Key, entry.Value)
+		}
+		trie := FromList(entries)
+		if trie.Hash() != incTrie.Hash() {
+			t.Fatalf(
+				"did not get expected root for %d entries: got %s, expected %s",
+				count,
+				trie.Hash(),
+				incTrie.Hash(),
+			)
+		}
+		if trie.Len() != incTrie.Len() {
+			t.Fatalf("did not get expected length: got %d, expected %d", trie.Len(), incTrie.Len())
+		}
+		// String only shows the structure of tries with a branch at the root
+		if _, ok := trie.rootNode.(*Branch); ok && trie.String() != incTrie.String() {
+			t.Fatalf("did not get expected trie structure:\n%s\nexpected:\n%s", trie, incTrie)
+		}
+		// The built trie is usable as a normal trie
+		for key, value := range incTrie.All() {
+			proof, err := trie.Prove(key)
+			if err != nil {
+				t.Fatalf("got unexpected error when generating proof: %s", err)
+			}
+			if !proof.Verify(trie.Hash(), key, value) {
+				t.Fatalf("proof did not verify for key %q", key)
+			}
+		}
+		for _, entry := range entries[:count/2] {
+			if trie.Has(entry.Key) {
+				if err := trie.Delete(entry.Key); err != nil {
+					t.Fatalf("got unexpected error when deleting key: %s", err)
+				}
+				if err := incTrie.Delete(entry.Key); err != nil {
+					t.Fatalf("got unexpected error when deleting key: %s", err)
+				}
+			}
+		}
+		trie.Set([]byte("extra"), []byte("value"))
+		incTrie.Set([]byte("extra"), []byte("value"))
+		if trie.Hash() != incTrie.Hash() || trie.Len() != incTrie.Len() {
+			t.Fatalf("built trie does not match incremental trie after changes")
+		}
+		if trie.LenPrefix([]Nibble{0x4}) != incTrie.LenPrefix([]Nibble{0x4}) {
+			t.Fatalf("did not get expected prefix length after changes")
+		}
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	expected := make(map[string]string)
+	incTrie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		expected[entry.key] = entry.value
+		incTrie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	seq := func(yield func([]byte, []byte) bool) {
+		for _, key := range slices.Sorted(maps.Keys(expected)) {
+			if !yield([]byte(key), []byte(expected[key])) {
+				return
+			}
+		}
+	}
+	trie := FromSeq(seq, WithProofCache())
+	if trie.Hash() != incTrie.Hash() {
+		t.Fatalf("did not get expected root: got %s, expected %s", trie.Hash(), incTrie.Hash())
+	}
+	if trie.proofCache == nil {
+		t.Fatalf("trie options were not applied")
+	}
+	// Building from the iterator of another trie gives the same trie
+	if rebuilt := FromSeq(trie.All()); rebuilt.Hash() != trie.Hash() {
+		t.Fatalf("did not get expected root from rebuilt trie: got %s, expected %s", rebuilt.Hash(), trie.Hash())
+	}
+	// An iterator which reuses its buffers for each key and value gives the same trie
+	reuseSeq := func(yield func([]byte, []byte) bool) {
+		var keyBuf, valueBuf []byte
+		for _, key := range slices.Sorted(maps.Keys(expected)) {
+			keyBuf = append(keyBuf[:0], key...)
+			valueBuf = append(valueBuf[:0], expected[key]...)
+			if !yield(keyBuf, valueBuf) {
+				return
+			}
+		}
+	}
+	reuseTrie := FromSeq(reuseSeq)
+	if reuseTrie.Hash() != incTrie.Hash() {
+		t.Fatalf("did not get expected root from reused buffers: got %s, expected %s", reuseTrie.Hash(), incTrie.Hash())
+	}
+	for key, value := range expected {
+		tmpValue, err := reuseTrie.Get([]byte(key))
+		if err != nil {
+			t.Fatalf("got unexpected error when getting key: %s", err)
+		}
+		if string(tmpValue) != value {
+			t.Fatalf("did not get expected value: got %q, expected %q", tmpValue, value)
+		}
+	}
+}
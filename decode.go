@@ -0,0 +1,385 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxProofSteps is the most steps a proof can have, since each step consumes at least one
+	// nibble of the key path
+	maxProofSteps = HashSize * 2
+
+	// maxForkPrefix is the longest prefix a fork neighbor can have, since the branch above it
+	// and the neighbor itself each consume at least one nibble of the key path
+	maxForkPrefix = HashSize*2 - 2
+
+	// defaultMaxProofBytes comfortably fits a proof with the maximum number of branch steps
+	defaultMaxProofBytes = 16 * 1024
+
+	// maxScanDepth is the deepest nesting of CBOR data items accepted when scanning a proof
+	maxScanDepth = 16
+)
+
+// CBOR major types used when scanning a proof
+const (
+	cborMajorBytes  = 2
+	cborMajorText   = 3
+	cborMajorList   = 4
+	cborMajorMap    = 5
+	cborMajorTag    = 6
+	cborMajorSimple = 7
+)
+
+// CBOR tags for the Plutus data constructors used by proof steps
+const (
+	cborTagConstructor0 = 121
+	cborTagConstructor1 = 122
+	cborTagConstructor2 = 123
+)
+
+// errScanListTooLong is returned when a scanned list has more than the allowed number of items
+var errScanListTooLong = errors.New("list has too many items")
+
+// Limits bounds the resources used when decoding a proof from untrusted input. A zero value
+// for any field uses the default
+type Limits struct {
+	// MaxSteps is the maximum number of proof steps. It is capped at 64, the most steps a
+	// proof for a 64 nibble key path can have
+	MaxSteps int
+	// MaxBytes is the maximum size of the encoded proof
+	MaxBytes int
+	// MaxForkPrefix is the maximum number of nibbles in the prefix of a fork neighbor. It is
+	// capped at 62, the longest prefix that fits in a 64 nibble key path
+	MaxForkPrefix int
+}
+
+// DefaultLimits returns the limits used by DecodeProof for fields left as zero
+func DefaultLimits() Limits {
+	return Limits{
+		MaxSteps:      maxProofSteps,
+		MaxBytes:      defaultMaxProofBytes,
+		MaxForkPrefix: maxForkPrefix,
+	}
+}
+
+// withDefaults returns the limits with zero fields replaced by the defaults and the other
+// fields capped at what a valid proof can need
+func (l Limits) withDefaults() Limits {
+	defaults := DefaultLimits()
+	if l.MaxSteps <= 0 || l.MaxSteps > maxProofSteps {
+		l.MaxSteps = defaults.MaxSteps
+	}
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = defaults.MaxBytes
+	}
+	if l.MaxForkPrefix <= 0 || l.MaxForkPrefix > maxForkPrefix {
+		l.MaxForkPrefix = defaults.MaxForkPrefix
+	}
+	return l
+}
+
+// DecodeProof decodes a proof from its CBOR encoding, as received from an untrusted source.
+// The encoding is scanned before anything is decoded, and any input exceeding the limits
+// fails with ErrLimitExceeded without allocating for it. The decoded proof must also pass
+// Validate
+func DecodeProof(data []byte, limits Limits) (*Proof, error) {
+	limits = limits.withDefaults()
+	if len(data) > limits.MaxBytes {
+		return nil, fmt.Errorf(
+			"%w: proof is %d bytes, limit is %d",
+			ErrLimitExceeded,
+			len(data),
+			limits.MaxBytes,
+		)
+	}
+	stepsData, err := scanProof(data, limits)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]ProofStep, len(stepsData))
+	for i, stepData := range stepsData {
+		if err := steps[i].UnmarshalCBOR(stepData); err != nil {
+			return nil, stepError(i, err)
+		}
+	}
+	ret := &Proof{steps: steps}
+	if err := ret.Validate(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// scanProof checks the structure of an encoded proof against the limits, returning the
+// encoded proof steps
+func scanProof(data []byte, limits Limits) ([][]byte, error) {
+	s := &cborScanner{data: data}
+	stepsData, err := s.listItems(0, limits.MaxSteps)
+	if err != nil {
+		if errors.Is(err, errScanListTooLong) {
+			return nil, fmt.Errorf(
+				"%w: proof has more than %d steps",
+				ErrLimitExceeded,
+				limits.MaxSteps,
+			)
+		}
+		return nil, fmt.Errorf("invalid proof: %w", err)
+	}
+	if s.pos != len(data) {
+		return nil, fmt.Errorf("trailing data after proof: %d bytes", len(data)-s.pos)
+	}
+	for i, stepData := range stepsData {
+		prefixLength, err := scanForkPrefixLength(stepData)
+		if err != nil {
+			return nil, stepError(i, err)
+		}
+		if prefixLength > uint64(limits.MaxForkPrefix) {
+			return nil, fmt.Errorf(
+				"%w: proof step %d: fork neighbor prefix is %d nibbles, limit is %d",
+				ErrLimitExceeded,
+				i,
+				prefixLength,
+				limits.MaxForkPrefix,
+			)
+		}
+	}
+	return stepsData, nil
+}
+
+// scanForkPrefixLength checks that the encoded proof step uses one of the proof step
+// constructors and returns the length of the fork neighbor prefix, or 0 for other steps
+func scanForkPrefixLength(data []byte) (uint64, error) {
+	s := &cborScanner{data: data}
+	major, tag, _, err := s.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if major != cborMajorTag || tag < cborTagConstructor0 || tag > cborTagConstructor2 {
+		return 0, errors.New("unexpected proof step encoding")
+	}
+	if tag != cborTagConstructor1 {
+		return 0, nil
+	}
+	// Fork steps have the fields [prefix length, neighbor], and the neighbor has the fields
+	// [nibble, prefix, root]
+	fields, err := s.listItems(1, 2)
+	if err != nil || len(fields) != 2 {
+		return 0, &stepFieldError{"neighbor", errors.New("fork step missing fields")}
+	}
+	s = &cborScanner{data: fields[1]}
+	major, tag, _, err = s.readHeader()
+	if err != nil || major != cborMajorTag || tag != cborTagConstructor0 {
+		return 0, &stepFieldError{"neighbor", errors.New("unexpected fork neighbor encoding")}
+	}
+	neighborFields, err := s.listItems(2, 3)
+	if err != nil || len(neighborFields) != 3 {
+		return 0, &stepFieldError{"neighbor", errors.New("fork neighbor missing fields")}
+	}
+	s = &cborScanner{data: neighborFields[1]}
+	prefixLength, err := s.byteStringLength()
+	if err != nil {
+		return 0, &stepFieldError{"neighbor.prefix", fmt.Errorf("invalid fork neighbor prefix: %w", err)}
+	}
+	return prefixLength, nil
+}
+
+// cborScanner walks the structure of CBOR data without decoding it, checking that every
+// length fits within the data
+type cborScanner struct {
+	data []byte
+	pos  int
+}
+
+// readHeader reads the header of the next data item, returning its major type and argument
+// or whether it has an indefinite length
+func (s *cborScanner) readHeader() (byte, uint64, bool, error) {
+	if s.pos >= len(s.data) {
+		return 0, 0, false, io.ErrUnexpectedEOF
+	}
+	initial := s.data[s.pos]
+	s.pos++
+	major := initial >> 5
+	info := initial & 0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(s.data)-s.pos < size {
+			return 0, 0, false, io.ErrUnexpectedEOF
+		}
+		var arg uint64
+		for _, b := range s.data[s.pos : s.pos+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		s.pos += size
+		return major, arg, false, nil
+	case info == 31 && major >= cborMajorBytes && major != cborMajorTag:
+		return major, 0, true, nil
+	}
+	return 0, 0, false, fmt.Errorf("invalid CBOR header 0x%02x at offset %d", initial, s.pos-1)
+}
+
+// atBreak returns whether the next byte is the break that ends an indefinite length item,
+// consuming it if so
+func (s *cborScanner) atBreak() (bool, error) {
+	if s.pos >= len(s.data) {
+		return false, io.ErrUnexpectedEOF
+	}
+	if s.data[s.pos] == 0xff {
+		s.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+// skipBytes skips the specified number of bytes of string content
+func (s *cborScanner) skipBytes(length uint64) error {
+	if length > uint64(len(s.data)-s.pos) {
+		return fmt.Errorf("string length %d exceeds remaining data", length)
+	}
+	s.pos += int(length)
+	return nil
+}
+
+// skipString skips the content of a byte or text string with the specified header,
+// returning the total length of the string
+func (s *cborScanner) skipString(major byte, length uint64, indefinite bool) (uint64, error) {
+	if !indefinite {
+		return length, s.skipBytes(length)
+	}
+	var total uint64
+	for {
+		done, err := s.atBreak()
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			return total, nil
+		}
+		chunkMajor, chunkLength, chunkIndefinite, err := s.readHeader()
+		if err != nil {
+			return 0, err
+		}
+		if chunkMajor != major || chunkIndefinite {
+			return 0, errors.New("invalid indefinite length string chunk")
+		}
+		if err := s.skipBytes(chunkLength); err != nil {
+			return 0, err
+		}
+		total += chunkLength
+	}
+}
+
+// byteStringLength reads a byte string, returning its length
+func (s *cborScanner) byteStringLength() (uint64, error) {
+	major, length, indefinite, err := s.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if major != cborMajorBytes {
+		return 0, errors.New("expected byte string")
+	}
+	return s.skipString(major, length, indefinite)
+}
+
+// listItems reads a list of at most maxItems items, returning the encoded items
+func (s *cborScanner) listItems(depth int, maxItems int) ([][]byte, error) {
+	major, count, indefinite, err := s.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMajorList {
+		return nil, errors.New("expected list")
+	}
+	if !indefinite && count > uint64(maxItems) {
+		return nil, errScanListTooLong
+	}
+	var ret [][]byte
+	for i := uint64(0); indefinite || i < count; i++ {
+		if indefinite {
+			done, err := s.atBreak()
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+			if len(ret) == maxItems {
+				return nil, errScanListTooLong
+			}
+		}
+		start := s.pos
+		if err := s.skipItem(depth + 1); err != nil {
+			return nil, err
+		}
+		ret = append(ret, s.data[start:s.pos])
+	}
+	return ret, nil
+}
+
+// skipItem skips the next data item, including any nested items
+func (s *cborScanner) skipItem(depth int) error {
+	if depth > maxScanDepth {
+		return errors.New("CBOR nesting is too deep")
+	}
+	major, arg, indefinite, err := s.readHeader()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborMajorBytes, cborMajorText:
+		_, err := s.skipString(major, arg, indefinite)
+		return err
+	case cborMajorList, cborMajorMap:
+		if !indefinite && arg > uint64(len(s.data)-s.pos) {
+			return fmt.Errorf("item count %d exceeds remaining data", arg)
+		}
+		itemsPerEntry := uint64(1)
+		if major == cborMajorMap {
+			itemsPerEntry = 2
+		}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite {
+				done, err := s.atBreak()
+				if err != nil {
+					return err
+				}
+				if done {
+					return nil
+				}
+			}
+			for range itemsPerEntry {
+				if err := s.skipItem(depth + 1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case cborMajorTag:
+		return s.skipItem(depth + 1)
+	case cborMajorSimple:
+		if indefinite {
+			return errors.New("unexpected break")
+		}
+		return nil
+	default:
+		// Integers are fully contained in the header
+		return nil
+	}
+}
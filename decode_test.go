@@ -0,0 +1,224 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// decodeTestTrie returns a trie whose proofs include branch, fork and leaf steps
+func decodeTestTrie() *Trie {
+	trie := NewTrie()
+	for i := range 200 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	return trie
+}
+
+func TestDecodeProof(t *testing.T) {
+	trie := decodeTestTrie()
+	for i := range 200 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		proof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		for _, encoding := range []CBOREncoding{EncodeIndefinite, EncodeDefinite} {
+			proofCbor, err := proof.MarshalCBORWithOptions(encoding)
+			if err != nil {
+				t.Fatalf("got unexpected error when encoding proof: %s", err)
+			}
+			decodedProof, err := DecodeProof(proofCbor, Limits{})
+			if err != nil {
+				t.Fatalf("got unexpected error when decoding proof: %s", err)
+			}
+			assertProofStepsEqual(t, decodedProof, proof)
+			if !decodedProof.Verify(trie.Hash(), key, fmt.Appendf(nil, "value-%d", i)) {
+				t.Fatalf("decoded proof did not verify for key %q", key)
+			}
+		}
+	}
+}
+
+func TestDecodeProofLimits(t *testing.T) {
+	branchStep, err := NewBranchStep(0, make([]Hash, branchProofNeighborCount))
+	if err != nil {
+		t.Fatalf("got unexpected error when creating branch step: %s", err)
+	}
+	forkStep, err := NewForkStep(0, 1, make([]Nibble, 10), NullHash)
+	if err != nil {
+		t.Fatalf("got unexpected error when creating fork step: %s", err)
+	}
+	manySteps := make([]ProofStep, maxProofSteps+1)
+	for i := range manySteps {
+		manySteps[i] = branchStep
+	}
+	testDefs := []struct {
+		name   string
+		proof  *Proof
+		limits Limits
+	}{
+		{
+			name:   "max bytes",
+			proof:  NewProof(branchStep, branchStep),
+			limits: Limits{MaxBytes: 200},
+		},
+		{
+			name:   "max steps",
+			proof:  NewProof(branchStep, branchStep, branchStep),
+			limits: Limits{MaxSteps: 2},
+		},
+		{
+			name:  "steps for a 64 nibble path",
+			proof: NewProof(manySteps...),
+			// The step limit can't be raised above what a key path allows
+			limits: Limits{MaxSteps: 1000, MaxBytes: 1 << 20},
+		},
+		{
+			name:   "max fork prefix",
+			proof:  NewProof(forkStep),
+			limits: Limits{MaxForkPrefix: 9},
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			for _, encoding := range []CBOREncoding{EncodeIndefinite, EncodeDefinite} {
+				proofCbor, err := testDef.proof.MarshalCBORWithOptions(encoding)
+				if err != nil {
+					t.Fatalf("got unexpected error when encoding proof: %s", err)
+				}
+				if _, err := DecodeProof(proofCbor, testDef.limits); !errors.Is(err, ErrLimitExceeded) {
+					t.Fatalf("did not get expected error: got %v, expected %v", err, ErrLimitExceeded)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeProofErrors(t *testing.T) {
+	testDefs := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "not a list",
+			data: []byte{0x01},
+		},
+		{
+			name: "truncated list",
+			data: []byte{0x9f, 0xd8, 0x79, 0x9f, 0x00},
+		},
+		{
+			// A byte string claiming far more data than is present
+			name: "oversized byte string",
+			data: []byte{0x81, 0xd8, 0x79, 0x82, 0x00, 0x5b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			// A list claiming far more items than are present
+			name: "oversized list",
+			data: []byte{0x81, 0xd8, 0x79, 0x9b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name: "unknown step constructor",
+			data: []byte{0x81, 0xd8, 0x7c, 0x80},
+		},
+		{
+			name: "trailing data",
+			data: []byte{0x80, 0x00},
+		},
+		{
+			name: "deep nesting",
+			data: append(
+				append([]byte{0x81, 0xd8, 0x79}, bytes.Repeat([]byte{0x81}, 100)...),
+				0x00,
+			),
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			if _, err := DecodeProof(testDef.data, Limits{}); err == nil {
+				t.Fatalf("expected error decoding %x but got nil", testDef.data)
+			}
+		})
+	}
+}
+
+// addProofStepSeeds adds the encoded steps from a selection of trie proofs to the fuzz corpus
+func addProofStepSeeds(f *testing.F) {
+	trie := decodeTestTrie()
+	for i := range 20 {
+		proof, err := trie.Prove(fmt.Appendf(nil, "key-%d", i))
+		if err != nil {
+			f.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		for _, step := range proof.steps {
+			for _, encoding := range []CBOREncoding{EncodeIndefinite, EncodeDefinite} {
+				stepCbor, err := step.MarshalCBORWithOptions(encoding)
+				if err != nil {
+					f.Fatalf("got unexpected error when encoding proof step: %s", err)
+				}
+				f.Add(stepCbor)
+			}
+		}
+	}
+}
+
+func FuzzProofStepUnmarshalCbor(f *testing.F) {
+	addProofStepSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var step ProofStep
+		if err := step.UnmarshalCBOR(data); err != nil {
+			return
+		}
+		// Anything that decodes should survive a round trip
+		stepCbor, err := step.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof step: %s", err)
+		}
+		var tmpStep ProofStep
+		if err := tmpStep.UnmarshalCBOR(stepCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding re-encoded proof step: %s", err)
+		}
+		assertProofStepsEqual(t, NewProof(tmpStep), NewProof(step))
+	})
+}
+
+func FuzzDecodeProof(f *testing.F) {
+	addProofStepSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Use the input both as a proof and as a single proof step
+		for _, proofData := range [][]byte{data, append([]byte{0x81}, data...)} {
+			proof, err := DecodeProof(proofData, Limits{})
+			if err != nil {
+				continue
+			}
+			if err := proof.Validate(); err != nil {
+				t.Fatalf("decoded proof failed validation: %s", err)
+			}
+			proofCbor, err := proof.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("got unexpected error when encoding proof: %s", err)
+			}
+			tmpProof, err := DecodeProof(proofCbor, Limits{})
+			if err != nil {
+				t.Fatalf("got unexpected error when decoding re-encoded proof: %s", err)
+			}
+			assertProofStepsEqual(t, tmpProof, proof)
+		}
+	})
+}
@@ -0,0 +1,280 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// ProofEnvelopeVersion is the version of the envelope format produced by this library
+const ProofEnvelopeVersion = 1
+
+// ProofEnvelope bundles a proof with everything needed to check it: the key path, the value
+// hash and the expected root. The original key and value are optional, and when present
+// they must hash to the key hash and value hash respectively
+type ProofEnvelope struct {
+	Key       []byte
+	KeyHash   Hash
+	Value     []byte
+	ValueHash Hash
+	Root      Hash
+	Proof     *Proof
+}
+
+// NewProofEnvelope returns an envelope for a proof that the specified key and value are
+// present in the trie with the specified root hash
+func NewProofEnvelope(root Hash, key []byte, value []byte, proof *Proof) *ProofEnvelope {
+	return &ProofEnvelope{
+		Key:       bytes.Clone(key),
+		KeyHash:   HashValue(key),
+		Value:     bytes.Clone(value),
+		ValueHash: HashValue(value),
+		Root:      root,
+		Proof:     proof,
+	}
+}
+
+// ProveEnvelope returns an envelope containing a proof for the specified key along with its
+// value and the current root hash, or ErrKeyNotExist if the key doesn't exist in the trie
+func (t *Trie) ProveEnvelope(key []byte) (*ProofEnvelope, error) {
+	value, err := t.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := t.Prove(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewProofEnvelope(t.Hash(), key, value, proof), nil
+}
+
+// Verify returns nil if the envelope is internally consistent and the proof shows that the
+// key and value are present in the trie with the envelope root hash
+func (e *ProofEnvelope) Verify() error {
+	if e.Proof == nil {
+		return errors.New("proof envelope missing proof")
+	}
+	if e.Key != nil && HashValue(e.Key) != e.KeyHash {
+		return errors.New("proof envelope key does not match key hash")
+	}
+	if e.Value != nil && HashValue(e.Value) != e.ValueHash {
+		return errors.New("proof envelope value does not match value hash")
+	}
+	tmpRoot, err := e.Proof.includingRoot(bytesToNibbles(e.KeyHash.Bytes()), e.ValueHash)
+	if err != nil {
+		return err
+	}
+	if tmpRoot != e.Root {
+		return ErrProofMismatch
+	}
+	return nil
+}
+
+// Open decodes a proof envelope in either its CBOR or JSON encoding and verifies it,
+// returning the envelope if the proof holds
+func Open(data []byte) (*ProofEnvelope, error) {
+	ret := &ProofEnvelope{}
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := ret.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := ret.UnmarshalCBOR(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := ret.Verify(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (e *ProofEnvelope) MarshalCBOR() ([]byte, error) {
+	if e.Proof == nil {
+		return nil, errors.New("proof envelope missing proof")
+	}
+	proofCbor, err := e.Proof.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	// Optional fields are encoded as null when absent
+	var tmpKey, tmpValue any
+	if e.Key != nil {
+		tmpKey = e.Key
+	}
+	if e.Value != nil {
+		tmpValue = e.Value
+	}
+	tmpData := []any{
+		ProofEnvelopeVersion,
+		e.KeyHash.Bytes(),
+		tmpKey,
+		e.ValueHash.Bytes(),
+		tmpValue,
+		e.Root.Bytes(),
+		cbor.RawMessage(proofCbor),
+	}
+	return cbor.Encode(&tmpData)
+}
+
+func (e *ProofEnvelope) UnmarshalCBOR(data []byte) error {
+	*e = ProofEnvelope{}
+	var fields []cbor.RawMessage
+	if err := decodeExact(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return errors.New("proof envelope missing version")
+	}
+	version, err := decodeNonNegativeInt(fields[0])
+	if err != nil {
+		return fmt.Errorf("invalid proof envelope version: %w", err)
+	}
+	if version != ProofEnvelopeVersion {
+		return fmt.Errorf("unsupported proof envelope version: %d", version)
+	}
+	if len(fields) != 7 {
+		return errors.New("proof envelope missing fields")
+	}
+	if e.KeyHash, err = decodeHash(fields[1]); err != nil {
+		return fmt.Errorf("invalid proof envelope key hash: %w", err)
+	}
+	if e.Key, err = decodeOptionalBytes(fields[2]); err != nil {
+		return fmt.Errorf("invalid proof envelope key: %w", err)
+	}
+	if e.ValueHash, err = decodeHash(fields[3]); err != nil {
+		return fmt.Errorf("invalid proof envelope value hash: %w", err)
+	}
+	if e.Value, err = decodeOptionalBytes(fields[4]); err != nil {
+		return fmt.Errorf("invalid proof envelope value: %w", err)
+	}
+	if e.Root, err = decodeHash(fields[5]); err != nil {
+		return fmt.Errorf("invalid proof envelope root: %w", err)
+	}
+	e.Proof = &Proof{}
+	if err := e.Proof.UnmarshalCBOR(fields[6]); err != nil {
+		return fmt.Errorf("invalid proof envelope proof: %w", err)
+	}
+	return nil
+}
+
+// proofEnvelopeJson is the JSON representation of a proof envelope. The proof uses the same
+// format as Proof.MarshalJSON
+type proofEnvelopeJson struct {
+	Version   int             `json:"version"`
+	Key       *string         `json:"key,omitempty"`
+	KeyHash   string          `json:"keyHash"`
+	Value     *string         `json:"value,omitempty"`
+	ValueHash string          `json:"valueHash"`
+	Root      string          `json:"root"`
+	Proof     json.RawMessage `json:"proof"`
+}
+
+func (e *ProofEnvelope) MarshalJSON() ([]byte, error) {
+	if e.Proof == nil {
+		return nil, errors.New("proof envelope missing proof")
+	}
+	proofJson, err := e.Proof.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	tmpData := proofEnvelopeJson{
+		Version:   ProofEnvelopeVersion,
+		KeyHash:   e.KeyHash.String(),
+		ValueHash: e.ValueHash.String(),
+		Root:      e.Root.String(),
+		Proof:     proofJson,
+	}
+	if e.Key != nil {
+		tmpKey := hex.EncodeToString(e.Key)
+		tmpData.Key = &tmpKey
+	}
+	if e.Value != nil {
+		tmpValue := hex.EncodeToString(e.Value)
+		tmpData.Value = &tmpValue
+	}
+	return json.Marshal(tmpData)
+}
+
+func (e *ProofEnvelope) UnmarshalJSON(data []byte) error {
+	*e = ProofEnvelope{}
+	var tmpData proofEnvelopeJson
+	if err := json.Unmarshal(data, &tmpData); err != nil {
+		return err
+	}
+	if tmpData.Version != ProofEnvelopeVersion {
+		return fmt.Errorf("unsupported proof envelope version: %d", tmpData.Version)
+	}
+	var err error
+	if e.KeyHash, err = hashFromHexString(tmpData.KeyHash); err != nil {
+		return fmt.Errorf("invalid proof envelope key hash: %w", err)
+	}
+	if tmpData.Key != nil {
+		if e.Key, err = hex.DecodeString(*tmpData.Key); err != nil {
+			return fmt.Errorf("invalid proof envelope key: %w", err)
+		}
+	}
+	if e.ValueHash, err = hashFromHexString(tmpData.ValueHash); err != nil {
+		return fmt.Errorf("invalid proof envelope value hash: %w", err)
+	}
+	if tmpData.Value != nil {
+		if e.Value, err = hex.DecodeString(*tmpData.Value); err != nil {
+			return fmt.Errorf("invalid proof envelope value: %w", err)
+		}
+	}
+	if e.Root, err = hashFromHexString(tmpData.Root); err != nil {
+		return fmt.Errorf("invalid proof envelope root: %w", err)
+	}
+	if tmpData.Proof == nil {
+		return errors.New("proof envelope missing proof")
+	}
+	e.Proof = &Proof{}
+	if err := e.Proof.UnmarshalJSON(tmpData.Proof); err != nil {
+		return fmt.Errorf("invalid proof envelope proof: %w", err)
+	}
+	return nil
+}
+
+// decodeHash decodes a CBOR byte string containing a hash
+func decodeHash(data []byte) (Hash, error) {
+	tmpBytes, err := decodeBytes(data)
+	if err != nil {
+		return NullHash, err
+	}
+	return hashFromBytes(tmpBytes)
+}
+
+// decodeOptionalBytes decodes a CBOR byte string, returning nil for a CBOR null
+func decodeOptionalBytes(data []byte) ([]byte, error) {
+	if bytes.Equal(data, []byte{0xf6}) {
+		return nil, nil
+	}
+	ret, err := decodeBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	// Keep an empty value distinct from a missing one
+	if ret == nil {
+		ret = []byte{}
+	}
+	return ret, nil
+}
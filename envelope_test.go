@@ -0,0 +1,185 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProofEnvelopeRoundTrip(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	for _, entry := range fruitsTestEntries {
+		envelope, err := trie.ProveEnvelope([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof envelope: %s", err)
+		}
+		if err := envelope.Verify(); err != nil {
+			t.Fatalf("proof envelope did not verify: %s", err)
+		}
+		envelopeCbor, err := envelope.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+		}
+		envelopeJson, err := envelope.MarshalJSON()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+		}
+		for _, data := range [][]byte{envelopeCbor, envelopeJson} {
+			opened, err := Open(data)
+			if err != nil {
+				t.Fatalf("got unexpected error when opening proof envelope: %s", err)
+			}
+			if !bytes.Equal(opened.Key, []byte(entry.key)) {
+				t.Fatalf("did not get expected key: got %q, expected %q", opened.Key, entry.key)
+			}
+			if !bytes.Equal(opened.Value, []byte(entry.value)) {
+				t.Fatalf(
+					"did not get expected value: got %q, expected %q",
+					opened.Value,
+					entry.value,
+				)
+			}
+			if opened.Root != trie.Hash() {
+				t.Fatalf("did not get expected root: got %s", opened.Root.String())
+			}
+			assertProofStepsEqual(t, opened.Proof, envelope.Proof)
+		}
+	}
+}
+
+func TestProofEnvelopeHashesOnly(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	envelope, err := trie.ProveEnvelope([]byte(fruitsTestEntries[7].key))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof envelope: %s", err)
+	}
+	envelope.Key = nil
+	envelope.Value = nil
+	envelopeCbor, err := envelope.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+	}
+	envelopeJson, err := envelope.MarshalJSON()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+	}
+	if strings.Contains(string(envelopeJson), `{"version":1,"key"`) {
+		t.Fatalf("JSON envelope contains key when it shouldn't: %s", envelopeJson)
+	}
+	for _, data := range [][]byte{envelopeCbor, envelopeJson} {
+		opened, err := Open(data)
+		if err != nil {
+			t.Fatalf("got unexpected error when opening proof envelope: %s", err)
+		}
+		if opened.Key != nil || opened.Value != nil {
+			t.Fatal("opened envelope contains key or value when it shouldn't")
+		}
+	}
+}
+
+func TestProofEnvelopeEmptyValue(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte{})
+	trie.Set([]byte("bcde"), []byte("2"))
+	envelope, err := trie.ProveEnvelope([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof envelope: %s", err)
+	}
+	envelopeCbor, err := envelope.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+	}
+	opened, err := Open(envelopeCbor)
+	if err != nil {
+		t.Fatalf("got unexpected error when opening proof envelope: %s", err)
+	}
+	if opened.Value == nil || len(opened.Value) != 0 {
+		t.Fatalf("did not get expected empty value: got %#v", opened.Value)
+	}
+}
+
+func TestProofEnvelopeRejectsTampering(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	envelope, err := trie.ProveEnvelope([]byte(fruitsTestEntries[3].key))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof envelope: %s", err)
+	}
+	// Value that doesn't match the value hash
+	tampered := *envelope
+	tampered.Value = []byte("wrong value")
+	if err := tampered.Verify(); err == nil {
+		t.Fatal("expected value hash error but got nil")
+	}
+	// Consistent value and hash which aren't in the trie
+	tampered = *envelope
+	tampered.Value = []byte("wrong value")
+	tampered.ValueHash = HashValue(tampered.Value)
+	if err := tampered.Verify(); !errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrProofMismatch)
+	}
+	envelopeCbor, err := tampered.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+	}
+	if _, err := Open(envelopeCbor); !errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrProofMismatch)
+	}
+	// Wrong root
+	tampered = *envelope
+	tampered.Root = NullHash
+	if err := tampered.Verify(); !errors.Is(err, ErrProofMismatch) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrProofMismatch)
+	}
+}
+
+func TestProofEnvelopeRejectsUnknownVersion(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	envelope, err := trie.ProveEnvelope([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof envelope: %s", err)
+	}
+	envelopeCbor, err := envelope.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+	}
+	// The version is the first item in the list
+	envelopeCbor[1] = 0x02
+	if _, err := Open(envelopeCbor); err == nil ||
+		!strings.Contains(err.Error(), "unsupported proof envelope version") {
+		t.Fatalf("did not get expected version error: got %v", err)
+	}
+	envelopeJson, err := envelope.MarshalJSON()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof envelope: %s", err)
+	}
+	envelopeJson = bytes.Replace(envelopeJson, []byte(`"version":1`), []byte(`"version":2`), 1)
+	if _, err := Open(envelopeJson); err == nil ||
+		!strings.Contains(err.Error(), "unsupported proof envelope version") {
+		t.Fatalf("did not get expected version error: got %v", err)
+	}
+}
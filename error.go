@@ -16,4 +16,24 @@ package mpf
 
 import "errors"
 
-var ErrKeyNotExist = errors.New("key does not exist")
+var (
+	ErrKeyNotExist   = errors.New("key does not exist")
+	ErrKeyExists     = errors.New("key already exists")
+	ErrProofMismatch = errors.New("proof does not match root")
+
+	// ErrPrefixNotExist is returned when no key path in the trie starts with a path prefix
+	ErrPrefixNotExist = errors.New("path prefix does not exist")
+
+	// ErrPrefixIsLeaf is returned when a path prefix is covered by a single leaf rather than
+	// a branch
+	ErrPrefixIsLeaf = errors.New("path prefix is covered by a single leaf")
+
+	// ErrInsufficientWitness is returned when a witness doesn't hold enough to complete an
+	// operation, such as refreshing a proof after a delete collapses a branch whose remaining
+	// child is only known by its hash, or verifying more operations than a transition
+	// witness covers
+	ErrInsufficientWitness = errors.New("insufficient witness")
+
+	// ErrLimitExceeded is returned by DecodeProof when the input exceeds the configured limits
+	ErrLimitExceeded = errors.New("proof exceeds decoding limits")
+)
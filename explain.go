@@ -0,0 +1,268 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ProofTrace describes how a proof is folded into a root hash for a key and value, with the
+// intermediate node hashes recomputed at each step
+type ProofTrace struct {
+	Key       []byte
+	Path      []Nibble
+	ValueHash Hash
+	// LeafSuffix is the part of the path below the last proof step
+	LeafSuffix []Nibble
+	LeafHash   Hash
+	// LeafExpected is the hash of the trie node in the position of the leaf, set by Compare
+	LeafExpected *Hash
+	// Steps are ordered from the root of the trie, matching the proof steps
+	Steps []ProofTraceStep
+	// Root is the recomputed root hash, which is only set when Err is nil
+	Root Hash
+	// Err is the error that stopped the root from being recomputed
+	Err error
+}
+
+// ProofTraceStep describes a single proof step and the branch node recomputed from it
+type ProofTraceStep struct {
+	Type ProofStepType
+	// Cursor is the path position at which the branch starts
+	Cursor int
+	// Prefix is the branch prefix, which is skipped over in the path
+	Prefix []Nibble
+	// Nibble is the child slot of the branch that holds the rest of the path
+	Nibble    Nibble
+	Neighbors []Hash
+	Neighbor  ProofStepNeighbor
+	// ChildHash is the hash of the node in the child slot that holds the rest of the path
+	ChildHash Hash
+	// Hash is the recomputed hash of the branch node
+	Hash Hash
+	// Expected is the hash of the trie node in the position of the branch, set by Compare
+	Expected *Hash
+	// Err is the error encountered when recomputing the branch node
+	Err error
+}
+
+// Explain returns a trace of how the proof commits to a root hash, assuming that the
+// specified key and value are present. Any error in the proof is recorded in the trace,
+// along with the hashes recomputed before it was encountered
+func (p *Proof) Explain(key []byte, value []byte) *ProofTrace {
+	path := keyToPath(key)
+	ret := &ProofTrace{
+		Key:       slices.Clone(key),
+		Path:      path,
+		ValueHash: HashValue(value),
+		Steps:     make([]ProofTraceStep, len(p.steps)),
+	}
+	for i, step := range p.steps {
+		ret.Steps[i] = ProofTraceStep{
+			Type:      step.stepType,
+			Neighbors: slices.Clone(step.neighbors),
+			Neighbor:  step.clone().neighbor,
+		}
+	}
+	cursors, err := p.stepCursors(path)
+	if err != nil {
+		ret.Err = err
+		return ret
+	}
+	for i := range p.steps {
+		nextCursor := cursors[i+1]
+		ret.Steps[i].Cursor = cursors[i]
+		ret.Steps[i].Prefix = path[cursors[i] : nextCursor-1]
+		ret.Steps[i].Nibble = path[nextCursor-1]
+	}
+	ret.LeafSuffix = path[cursors[len(p.steps)]:]
+	ret.LeafHash = leafHash(ret.LeafSuffix, ret.ValueHash)
+	childHash := ret.LeafHash
+	for i := len(p.steps) - 1; i >= 0; i-- {
+		traceStep := &ret.Steps[i]
+		traceStep.ChildHash = childHash
+		childHash, err = p.steps[i].nodeHash(path, cursors[i], childHash)
+		if err != nil {
+			traceStep.Err = err
+			ret.Err = stepError(i, err)
+			return ret
+		}
+		traceStep.Hash = childHash
+	}
+	ret.Root = childHash
+	return ret
+}
+
+// Compare fills in the expected hashes from the nodes of the trie along the path, and
+// returns the index of the step at which the recomputed hashes stop matching the trie when
+// working up from the leaf. The index is len(Steps) when the leaf itself doesn't match, and
+// -1 when everything matches
+func (t *ProofTrace) Compare(trie *Trie) int {
+	nodeHashes := trieNodeHashes(trie.rootNode, t.Path)
+	ret := -1
+	if t.LeafSuffix != nil {
+		if tmpHash, ok := nodeHashes[len(t.Path)-len(t.LeafSuffix)]; ok {
+			t.LeafExpected = &tmpHash
+		}
+		if t.LeafExpected == nil || *t.LeafExpected != t.LeafHash {
+			ret = len(t.Steps)
+		}
+	}
+	for i := len(t.Steps) - 1; i >= 0; i-- {
+		step := &t.Steps[i]
+		if tmpHash, ok := nodeHashes[step.Cursor]; ok && step.Prefix != nil {
+			step.Expected = &tmpHash
+		}
+		if ret == -1 && (step.Expected == nil || *step.Expected != step.Hash) {
+			ret = i
+		}
+	}
+	return ret
+}
+
+// CompareHashes fills in the expected hashes from a list of known node hashes along the
+// path, without needing the trie. The list is ordered from the root of the trie, with one
+// hash for the branch of each step followed by the hash of the leaf, and may stop early when
+// only the hashes nearer the root are known, such as when only the root hash is known. Like
+// Compare, it returns the index of the step at which the recomputed hashes stop matching
+// when working up from the leaf, or len(Steps) for the leaf, but only positions with a known
+// hash are checked. Returns -1 when all of the known hashes match
+func (t *ProofTrace) CompareHashes(expected []Hash) int {
+	if len(expected) > len(t.Steps)+1 {
+		expected = expected[:len(t.Steps)+1]
+	}
+	ret := -1
+	if len(expected) == len(t.Steps)+1 {
+		tmpHash := expected[len(t.Steps)]
+		t.LeafExpected = &tmpHash
+		if tmpHash != t.LeafHash {
+			ret = len(t.Steps)
+		}
+	}
+	for i := min(len(expected), len(t.Steps)) - 1; i >= 0; i-- {
+		tmpHash := expected[i]
+		t.Steps[i].Expected = &tmpHash
+		if ret == -1 && (t.Steps[i].Err != nil || tmpHash != t.Steps[i].Hash) {
+			ret = i
+		}
+	}
+	return ret
+}
+
+// CompareRoot fills in the expected hash of the top node from the specified root hash, and
+// returns whether the recomputed root matches it
+func (t *ProofTrace) CompareRoot(root Hash) bool {
+	t.CompareHashes([]Hash{root})
+	return t.Err == nil && t.Root == root
+}
+
+// trieNodeHashes returns the hashes of the trie nodes along the path, keyed by the path
+// position at which each node starts
+func trieNodeHashes(node Node, path []Nibble) map[int]Hash {
+	ret := make(map[int]Hash)
+	cursor := 0
+	for node != nil {
+		ret[cursor] = node.Hash()
+		branch, ok := node.(*Branch)
+		if !ok {
+			break
+		}
+		nextCursor := cursor + len(branch.prefix)
+		if nextCursor >= len(path) || !slices.Equal(path[cursor:nextCursor], branch.prefix) {
+			break
+		}
+		node = branch.children[path[nextCursor]]
+		cursor = nextCursor + 1
+	}
+	return ret
+}
+
+// String returns the trace as text, with the steps ordered from the root of the trie
+func (t *ProofTrace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "key %x\n", t.Key)
+	fmt.Fprintf(&sb, "path %s\n", nibblesToHexString(t.Path))
+	if t.Err == nil {
+		fmt.Fprintf(&sb, "root #%s\n", t.Root.String())
+	} else {
+		fmt.Fprintf(&sb, "error: %s\n", t.Err)
+	}
+	for i, step := range t.Steps {
+		fmt.Fprintf(&sb, "step %d %s:", i, step.Type)
+		if step.Prefix != nil {
+			fmt.Fprintf(
+				&sb,
+				" cursor %d, prefix %q, slot %s",
+				step.Cursor,
+				nibblesToHexString(step.Prefix),
+				step.Nibble,
+			)
+		}
+		sb.WriteByte('\n')
+		switch step.Type {
+		case ProofStepTypeBranch:
+			for j, neighbor := range step.Neighbors {
+				fmt.Fprintf(&sb, "  neighbor %d #%s\n", j, neighbor.String())
+			}
+		case ProofStepTypeFork:
+			fmt.Fprintf(
+				&sb,
+				"  neighbor slot %s, prefix %q, root #%s\n",
+				step.Neighbor.nibble,
+				nibblesToHexString(step.Neighbor.prefix),
+				step.Neighbor.root.String(),
+			)
+		case ProofStepTypeLeaf:
+			fmt.Fprintf(
+				&sb,
+				"  neighbor key %s, value #%s\n",
+				nibblesToHexString(step.Neighbor.key),
+				step.Neighbor.value.String(),
+			)
+		}
+		switch {
+		case step.Err != nil:
+			fmt.Fprintf(&sb, "  error: %s\n", step.Err)
+		case step.Hash != Hash{}:
+			writeTraceHash(&sb, step.Hash, step.Expected)
+		}
+	}
+	if t.LeafSuffix != nil {
+		fmt.Fprintf(
+			&sb,
+			"leaf: suffix %q, value #%s\n",
+			nibblesToHexString(t.LeafSuffix),
+			t.ValueHash.String(),
+		)
+		writeTraceHash(&sb, t.LeafHash, t.LeafExpected)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// writeTraceHash writes a recomputed node hash, noting whether it matches the expected hash
+// if one is known
+func writeTraceHash(sb *strings.Builder, nodeHash Hash, expected *Hash) {
+	fmt.Fprintf(sb, "  hash #%s", nodeHash.String())
+	if expected != nil {
+		if *expected == nodeHash {
+			sb.WriteString(" (matches)")
+		} else {
+			fmt.Fprintf(sb, " (expected #%s)", expected.String())
+		}
+	}
+	sb.WriteByte('\n')
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProofExplain(t *testing.T) {
+	trie := NewTrie()
+	for i := range 200 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	for i := range 200 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		value := fmt.Appendf(nil, "value-%d", i)
+		proof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		trace := proof.Explain(key, value)
+		if trace.Err != nil {
+			t.Fatalf("got unexpected error in trace: %s", trace.Err)
+		}
+		if trace.Root != trie.Hash() {
+			t.Fatalf("did not get expected root: got %s, expected %s", trace.Root, trie.Hash())
+		}
+		if len(trace.Steps) != len(proof.steps) {
+			t.Fatalf("did not get expected step count: got %d, expected %d", len(trace.Steps), len(proof.steps))
+		}
+		if mismatchIdx := trace.Compare(trie); mismatchIdx != -1 {
+			t.Fatalf("got unexpected mismatch at step %d:\n%s", mismatchIdx, trace)
+		}
+		traceStr := trace.String()
+		if !strings.Contains(traceStr, "root #"+trie.Hash().String()) ||
+			strings.Contains(traceStr, "expected #") {
+			t.Fatalf("did not get expected trace output:\n%s", traceStr)
+		}
+		// A wrong value stops matching at the leaf
+		trace = proof.Explain(key, []byte("wrong value"))
+		if mismatchIdx := trace.Compare(trie); mismatchIdx != len(trace.Steps) {
+			t.Fatalf("did not get expected mismatch at leaf: got %d, expected %d", mismatchIdx, len(trace.Steps))
+		}
+	}
+}
+
+func TestProofExplainTamperedStep(t *testing.T) {
+	trie := NewTrie()
+	for i := range 200 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	key := []byte("key-0")
+	proof, err := trie.Prove(key)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	// Tamper with the deepest branch step
+	tamperIdx := -1
+	for i, step := range proof.steps {
+		if step.stepType == ProofStepTypeBranch {
+			tamperIdx = i
+		}
+	}
+	if tamperIdx < 0 {
+		t.Fatalf("proof has no branch steps")
+	}
+	proof.steps[tamperIdx].neighbors[0] = HashValue([]byte("tampered"))
+	trace := proof.Explain(key, []byte("value-0"))
+	if mismatchIdx := trace.Compare(trie); mismatchIdx != tamperIdx {
+		t.Fatalf("did not get expected mismatch: got step %d, expected step %d\n%s", mismatchIdx, tamperIdx, trace)
+	}
+	if !strings.Contains(trace.String(), "expected #") {
+		t.Fatalf("did not get expected mismatch in trace output:\n%s", trace)
+	}
+}
+
+func TestProofExplainMalformed(t *testing.T) {
+	key := []byte("abcd")
+	branchStep, err := NewBranchStep(0, make([]Hash, branchProofNeighborCount))
+	if err != nil {
+		t.Fatalf("got unexpected error when creating branch step: %s", err)
+	}
+	forkStep, err := NewForkStep(0, keyToPath(key)[1], nil, NullHash)
+	if err != nil {
+		t.Fatalf("got unexpected error when creating fork step: %s", err)
+	}
+	trace := NewProof(branchStep, forkStep).Explain(key, []byte("1"))
+	assertMalformedProofError(t, trace.Err, 1, "neighbor.nibble")
+	if trace.Steps[1].Err == nil || trace.Steps[1].ChildHash != trace.LeafHash {
+		t.Fatalf("did not get expected trace for failed step: %+v", trace.Steps[1])
+	}
+	var malformedErr *MalformedProofError
+	if !errors.As(trace.Err, &malformedErr) || !strings.Contains(trace.String(), "error: ") {
+		t.Fatalf("did not get expected trace output:\n%s", trace)
+	}
+}
+
+func TestProofExplainCompareHashes(t *testing.T) {
+	trie := NewTrie()
+	for i := range 200 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	key := []byte("key-0")
+	value := []byte("value-0")
+	proof, err := trie.Prove(key)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	// Collect the node hashes from a good proof, as an indexer might have recorded them
+	goodTrace := proof.Explain(key, value)
+	var expected []Hash
+	for _, step := range goodTrace.Steps {
+		expected = append(expected, step.Hash)
+	}
+	expected = append(expected, goodTrace.LeafHash)
+	if mismatchIdx := goodTrace.CompareHashes(expected); mismatchIdx != -1 {
+		t.Fatalf("got unexpected mismatch at step %d:\n%s", mismatchIdx, goodTrace)
+	}
+	if !goodTrace.CompareRoot(trie.Hash()) {
+		t.Fatalf("trace did not reach expected root:\n%s", goodTrace)
+	}
+	// Tamper with the deepest branch step
+	tamperIdx := -1
+	for i, step := range proof.steps {
+		if step.stepType == ProofStepTypeBranch {
+			tamperIdx = i
+		}
+	}
+	if tamperIdx < 1 {
+		t.Fatalf("proof has no branch steps below the root")
+	}
+	proof.steps[tamperIdx].neighbors[0] = HashValue([]byte("tampered"))
+	trace := proof.Explain(key, value)
+	if trace.CompareRoot(trie.Hash()) {
+		t.Fatalf("tampered trace reached expected root:\n%s", trace)
+	}
+	if !strings.Contains(trace.String(), "expected #"+trie.Hash().String()) {
+		t.Fatalf("did not get expected root mismatch in trace output:\n%s", trace)
+	}
+	testDefs := []struct {
+		expected    []Hash
+		mismatchIdx int
+	}{
+		{expected: expected, mismatchIdx: tamperIdx},
+		{expected: []Hash{trie.Hash()}, mismatchIdx: 0},
+		{expected: expected[:tamperIdx], mismatchIdx: tamperIdx - 1},
+		{expected: nil, mismatchIdx: -1},
+	}
+	for _, testDef := range testDefs {
+		trace := proof.Explain(key, value)
+		if mismatchIdx := trace.CompareHashes(testDef.expected); mismatchIdx != testDef.mismatchIdx {
+			t.Fatalf(
+				"did not get expected mismatch with %d known hashes: got step %d, expected step %d",
+				len(testDef.expected),
+				mismatchIdx,
+				testDef.mismatchIdx,
+			)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// Entry is a key and value stored in a trie
+type Entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// Cursor is an opaque position in the hash path order of a trie, returned by Scan to resume
+// from where the previous page ended
+type Cursor []byte
+
+// All returns an iterator over the keys and values in the trie, ordered by the hash path of
+// each key. The returned slices must not be modified, and the trie must not be modified
+// while iterating
+func (t *Trie) All() iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		walkLeaves(t.rootNode, func(l *Leaf) bool {
+			return yield(l.key, l.value)
+		})
+	}
+}
+
+// Keys returns an iterator over the keys in the trie, in the same order as All
+func (t *Trie) Keys() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		walkLeaves(t.rootNode, func(l *Leaf) bool {
+			return yield(l.key)
+		})
+	}
+}
+
+// Values returns an iterator over the values in the trie, in the same order as All
+func (t *Trie) Values() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		walkLeaves(t.rootNode, func(l *Leaf) bool {
+			return yield(l.value)
+		})
+	}
+}
+
+// Scan returns up to limit entries whose key hash path starts with the specified prefix,
+// ordered as with All and starting after the position of the cursor. A nil cursor starts
+// from the beginning. The returned cursor resumes after the last returned entry, and is nil
+// when there are no more entries. Since the cursor holds a position rather than a reference
+// to the trie, it remains valid while the trie is modified, and a resumed scan includes any
+// entries added after that position. The returned entries are copies which share no memory
+// with the trie
+func (t *Trie) Scan(pathPrefix []Nibble, cursor Cursor, limit int) ([]Entry, Cursor, error) {
+	if err := validateNibbles(pathPrefix); err != nil {
+		return nil, nil, fmt.Errorf("invalid path prefix: %w", err)
+	}
+	if len(pathPrefix) > HashSize*2 {
+		return nil, nil, fmt.Errorf("path prefix is too long: %d", len(pathPrefix))
+	}
+	if limit <= 0 {
+		return nil, nil, fmt.Errorf("invalid limit: %d", limit)
+	}
+	var after []Nibble
+	if cursor != nil {
+		if len(cursor) != HashSize {
+			return nil, nil, errors.New("invalid cursor")
+		}
+		after = bytesToNibbles(cursor)
+	}
+	var ret []Entry
+	var retCursor Cursor
+	var lastPath []Nibble
+	scanLeaves(t.rootNode, nil, pathPrefix, after, func(path []Nibble, l *Leaf) bool {
+		if len(ret) == limit {
+			// There is at least one more entry, so return a cursor to resume from
+			retCursor = nibblesToBytes(lastPath)
+			return false
+		}
+		ret = append(ret, Entry{Key: bytes.Clone(l.key), Value: bytes.Clone(l.value)})
+		lastPath = path
+		return true
+	})
+	return ret, retCursor, nil
+}
+
+// scanLeaves calls fn in path order for each leaf under the node whose full path starts with
+// the prefix and comes after the specified path, stopping early if fn returns false. The
+// path is the part of the full path leading to the node, and subtrees which can't contain
+// a matching leaf are skipped without visiting them. Returns whether the scan completed
+func scanLeaves(
+	node Node,
+	path []Nibble,
+	prefix []Nibble,
+	after []Nibble,
+	fn func([]Nibble, *Leaf) bool,
+) bool {
+	switch n := node.(type) {
+	case *Leaf:
+		fullPath := slices.Concat(path, n.suffix)
+		if len(fullPath) < len(prefix) || !slices.Equal(fullPath[:len(prefix)], prefix) {
+			return true
+		}
+		if after != nil && slices.Compare(fullPath, after) <= 0 {
+			return true
+		}
+		return fn(fullPath, n)
+	case *Branch:
+		branchPath := slices.Concat(path, n.prefix)
+		// Skip the branch if its paths diverge from the prefix
+		cmnLen := min(len(branchPath), len(prefix))
+		if !slices.Equal(branchPath[:cmnLen], prefix[:cmnLen]) {
+			return true
+		}
+		// Skip the branch if all of its paths come before the lower bound, and drop the
+		// lower bound if they all come after it
+		if after != nil {
+			switch slices.Compare(branchPath, after[:len(branchPath)]) {
+			case -1:
+				return true
+			case 1:
+				after = nil
+			}
+		}
+		for idx, child := range n.children {
+			if child == nil {
+				continue
+			}
+			if len(branchPath) < len(prefix) && Nibble(idx) != prefix[len(branchPath)] {
+				continue
+			}
+			childAfter := after
+			if after != nil {
+				if Nibble(idx) < after[len(branchPath)] {
+					continue
+				}
+				if Nibble(idx) > after[len(branchPath)] {
+					childAfter = nil
+				}
+			}
+			childPath := slices.Concat(branchPath, []Nibble{Nibble(idx)})
+			if !scanLeaves(child, childPath, prefix, childAfter, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// walkLeaves calls fn for each leaf under the node in path order, stopping early if fn
+// returns false. Returns whether the walk completed
+func walkLeaves(node Node, fn func(*Leaf) bool) bool {
+	switch n := node.(type) {
+	case *Leaf:
+		return fn(n)
+	case *Branch:
+		for _, child := range n.children {
+			if child == nil {
+				continue
+			}
+			if !walkLeaves(child, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestTrieAll(t *testing.T) {
+	trie := NewTrie()
+	expected := make(map[string]string)
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+		expected[entry.key] = entry.value
+	}
+	var keys [][]byte
+	for key, value := range trie.All() {
+		if expected[string(key)] != string(value) {
+			t.Fatalf("did not get expected value for key %q: got %q, expected %q", key, value, expected[string(key)])
+		}
+		delete(expected, string(key))
+		keys = append(keys, key)
+	}
+	if len(expected) != 0 {
+		t.Fatalf("iteration is missing %d entries", len(expected))
+	}
+	// Entries are in hash path order
+	if !slices.IsSortedFunc(keys, func(a, b []byte) int {
+		return slices.Compare(keyToPath(a), keyToPath(b))
+	}) {
+		t.Fatalf("entries are not in hash path order")
+	}
+	if !slices.EqualFunc(slices.Collect(trie.Keys()), keys, slices.Equal) {
+		t.Fatalf("keys do not match entries")
+	}
+	var values [][]byte
+	for _, key := range keys {
+		value, err := trie.Get(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when getting key: %s", err)
+		}
+		values = append(values, value)
+	}
+	if !slices.EqualFunc(slices.Collect(trie.Values()), values, slices.Equal) {
+		t.Fatalf("values do not match entries")
+	}
+	// Stopping early yields only the requested entries
+	count := 0
+	for range trie.All() {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Fatalf("did not get expected entry count when stopping early: got %d, expected 3", count)
+	}
+}
+
+func TestTrieAllEmpty(t *testing.T) {
+	trie := NewTrie()
+	for range trie.All() {
+		t.Fatalf("got unexpected entry from empty trie")
+	}
+	trie.Set([]byte("abcd"), []byte("1"))
+	if keys := slices.Collect(trie.Keys()); len(keys) != 1 || string(keys[0]) != "abcd" {
+		t.Fatalf("did not get expected keys for single entry trie: %q", keys)
+	}
+}
+
+func TestTrieScan(t *testing.T) {
+	trie := NewTrie()
+	for i := range 500 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	allKeys := slices.Collect(trie.Keys())
+	prefixes := [][]Nibble{nil, {0x3}, {0xa, 0x1}, keyToPath(allKeys[7])[:5], keyToPath(allKeys[9])}
+	for _, prefix := range prefixes {
+		var expected [][]byte
+		for _, key := range allKeys {
+			if slices.Equal(keyToPath(key)[:len(prefix)], prefix) {
+				expected = append(expected, key)
+			}
+		}
+		for _, limit := range []int{1, 7, 1000} {
+			var keys [][]byte
+			var cursor Cursor
+			for {
+				entries, nextCursor, err := trie.Scan(prefix, cursor, limit)
+				if err != nil {
+					t.Fatalf("got unexpected error when scanning: %s", err)
+				}
+				if len(entries) > limit || (nextCursor != nil && len(entries) != limit) {
+					t.Fatalf("did not get expected page size: got %d, limit %d", len(entries), limit)
+				}
+				for _, entry := range entries {
+					value, err := trie.Get(entry.Key)
+					if err != nil || !bytes.Equal(value, entry.Value) {
+						t.Fatalf("did not get expected value for key %q", entry.Key)
+					}
+					keys = append(keys, entry.Key)
+				}
+				if nextCursor == nil {
+					break
+				}
+				cursor = nextCursor
+			}
+			if !slices.EqualFunc(keys, expected, bytes.Equal) {
+				t.Fatalf(
+					"did not get expected keys for prefix %s with limit %d: got %d keys, expected %d",
+					nibblesToHexString(prefix),
+					limit,
+					len(keys),
+					len(expected),
+				)
+			}
+		}
+	}
+}
+
+func TestTrieScanCursorAcrossMutations(t *testing.T) {
+	trie := NewTrie()
+	for i := range 200 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	entries, cursor, err := trie.Scan(nil, nil, 50)
+	if err != nil {
+		t.Fatalf("got unexpected error when scanning: %s", err)
+	}
+	lastPath := keyToPath(entries[len(entries)-1].Key)
+	// Delete the last returned entry and add more keys, which restructures the trie
+	if err := trie.Delete(entries[len(entries)-1].Key); err != nil {
+		t.Fatalf("got unexpected error when deleting key: %s", err)
+	}
+	for i := 200; i < 400; i++ {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	var keys [][]byte
+	for cursor != nil {
+		var page []Entry
+		page, cursor, err = trie.Scan(nil, cursor, 50)
+		if err != nil {
+			t.Fatalf("got unexpected error when scanning: %s", err)
+		}
+		for _, entry := range page {
+			keys = append(keys, entry.Key)
+		}
+	}
+	// The rest of the scan has every current key after the cursor position
+	var expected [][]byte
+	for key := range trie.Keys() {
+		if slices.Compare(keyToPath(key), lastPath) > 0 {
+			expected = append(expected, key)
+		}
+	}
+	if !slices.EqualFunc(keys, expected, bytes.Equal) {
+		t.Fatalf("did not get expected keys after mutations: got %d keys, expected %d", len(keys), len(expected))
+	}
+}
+
+func TestTrieScanErrors(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	if _, _, err := trie.Scan([]Nibble{0x10}, nil, 10); err == nil {
+		t.Fatalf("expected error for invalid prefix but got nil")
+	}
+	if _, _, err := trie.Scan(nil, Cursor{0x01}, 10); err == nil {
+		t.Fatalf("expected error for invalid cursor but got nil")
+	}
+	if _, _, err := trie.Scan(nil, nil, 0); err == nil {
+		t.Fatalf("expected error for invalid limit but got nil")
+	}
+	// Modifying returned entries doesn't change the trie
+	entries, _, err := trie.Scan(nil, nil, 10)
+	if err != nil {
+		t.Fatalf("got unexpected error when scanning: %s", err)
+	}
+	entries[0].Key[0] = 'x'
+	entries[0].Value[0] = 'x'
+	if value, err := trie.Get([]byte("abcd")); err != nil || string(value) != "1" {
+		t.Fatalf("trie was changed by modifying scanned entry: value %q, error %v", value, err)
+	}
+	entries, cursor, err := NewTrie().Scan(nil, nil, 10)
+	if err != nil || len(entries) != 0 || cursor != nil {
+		t.Fatalf("did not get expected empty scan: %d entries, cursor %x, error %v", len(entries), cursor, err)
+	}
+}
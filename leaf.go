@@ -63,7 +63,7 @@ func (l *Leaf) Set(value []byte) {
 	l.updateHash()
 }
 
-func (l *Leaf) generateProof(path []Nibble) (*Proof, error) {
+func (l *Leaf) generateProof(path []Nibble, _ *proofCache) (*Proof, error) {
 	if string(path) != string(l.suffix) {
 		return nil, ErrKeyNotExist
 	}
@@ -79,15 +79,40 @@ func (l *Leaf) generateProof(path []Nibble) (*Proof, error) {
 	return proof, nil
 }
 
+func (l *Leaf) generateExclusionProof(path []Nibble, _ *proofCache) (*Proof, error) {
+	if string(path) == string(l.suffix) {
+		return nil, ErrKeyExists
+	}
+	// The key would be inserted alongside this leaf in a new branch, so the leaf becomes
+	// the only neighbor at that point in the path
+	proof := newProof(nil, nil)
+	proof.steps = append(
+		proof.steps,
+		ProofStep{
+			stepType:     ProofStepTypeLeaf,
+			prefixLength: len(commonPrefix(path, l.suffix)),
+			neighbor: ProofStepNeighbor{
+				key:   keyToPath(l.key),
+				value: HashValue(l.value),
+			},
+		},
+	)
+	return proof, nil
+}
+
 func (l *Leaf) updateHash() {
-	tmpVal := make([]byte, 0, 2+(len(l.suffix)+1)/2+HashSize)
-	head := hashHead(l.suffix)
+	l.hash = leafHash(l.suffix, HashValue(l.value))
+}
+
+// leafHash calculates the hash for a leaf node with the specified suffix and value hash
+func leafHash(suffix []Nibble, valueHash Hash) Hash {
+	tmpVal := make([]byte, 0, 2+(len(suffix)+1)/2+HashSize)
+	head := hashHead(suffix)
 	tmpVal = append(tmpVal, head...)
-	tail := hashTail(l.suffix)
+	tail := hashTail(suffix)
 	tmpVal = append(tmpVal, tail...)
-	valueHash := HashValue(l.value)
 	tmpVal = append(tmpVal, valueHash.Bytes()...)
-	l.hash = HashValue(tmpVal)
+	return HashValue(tmpVal)
 }
 
 func hashHead(suffix []Nibble) []byte {
@@ -0,0 +1,160 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// MultiProof holds proofs for several keys against the same trie. The proofs are checked in
+// order, and each one leaves out the hashes that can be computed from the keys and proofs
+// before it, such as the neighbors near the root which cover keys that were already proven.
+// Steps which are identical once those hashes are left out are stored only once
+type MultiProof struct {
+	table *proofTable
+}
+
+// ProveMany returns a combined proof that all of the given keys exist in the trie or
+// ErrKeyNotExist if any of the keys doesn't exist in the trie
+func (t *Trie) ProveMany(keys [][]byte) (*MultiProof, error) {
+	ret := &MultiProof{
+		table: newProofTable(),
+	}
+	known := newKnownHashes()
+	for _, key := range keys {
+		proof, err := t.Prove(key)
+		if err != nil {
+			return nil, err
+		}
+		path := keyToPath(key)
+		if err := ret.table.add(known, path, proof); err != nil {
+			return nil, err
+		}
+		if err := known.learn(path, proof, true, HashValue(proof.value)); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Len returns the number of individual proofs
+func (m *MultiProof) Len() int {
+	return len(m.table.proofs)
+}
+
+// Proofs returns the individual proofs for the specified keys and values, which must be given
+// in the same order as the keys passed to ProveMany. The values are needed to fill in the
+// hashes that each proof leaves out, and all proofs must agree on the root
+func (m *MultiProof) Proofs(keys [][]byte, values [][]byte) ([]*Proof, error) {
+	ret, _, err := m.expand(keys, values)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ComputeRoot returns the root hash of the trie that the proofs commit to, assuming that
+// the specified keys and values are present in it. The keys and values must be given in the
+// same order as the keys passed to ProveMany
+func (m *MultiProof) ComputeRoot(keys [][]byte, values [][]byte) (Hash, error) {
+	_, ret, err := m.expand(keys, values)
+	if err != nil {
+		return NullHash, err
+	}
+	return ret, nil
+}
+
+// expand returns the individual proofs along with the root hash that they all commit to
+func (m *MultiProof) expand(keys [][]byte, values [][]byte) ([]*Proof, Hash, error) {
+	if len(keys) != len(m.table.proofs) || len(values) != len(m.table.proofs) {
+		return nil, NullHash, fmt.Errorf(
+			"key/value count does not match proof count: got %d keys and %d values, want %d",
+			len(keys),
+			len(values),
+			len(m.table.proofs),
+		)
+	}
+	if len(m.table.proofs) == 0 {
+		return nil, NullHash, errors.New("multi-proof contains no proofs")
+	}
+	known := newKnownHashes()
+	ret := make([]*Proof, 0, len(m.table.proofs))
+	var root Hash
+	for idx := range m.table.proofs {
+		path := keyToPath(keys[idx])
+		proof, err := m.table.proof(known, idx, path)
+		if err != nil {
+			return nil, NullHash, fmt.Errorf("proof %d: %w", idx, err)
+		}
+		valueHash := HashValue(values[idx])
+		tmpRoot, err := proof.includingRoot(path, valueHash)
+		if err != nil {
+			return nil, NullHash, fmt.Errorf("proof %d: %w", idx, err)
+		}
+		if idx > 0 && tmpRoot != root {
+			return nil, NullHash, fmt.Errorf("proof %d: root does not match previous proofs", idx)
+		}
+		if err := known.learn(path, proof, true, valueHash); err != nil {
+			return nil, NullHash, fmt.Errorf("proof %d: %w", idx, err)
+		}
+		root = tmpRoot
+		ret = append(ret, proof)
+	}
+	return ret, root, nil
+}
+
+// Verify returns whether the proofs show that all of the specified keys and values are
+// present in the trie with the specified root hash
+func (m *MultiProof) Verify(root Hash, keys [][]byte, values [][]byte) bool {
+	tmpRoot, err := m.ComputeRoot(keys, values)
+	if err != nil {
+		return false
+	}
+	return tmpRoot == root
+}
+
+// MarshalCBOR returns the CBOR encoding of the multi-proof, which is a list of the distinct
+// steps followed by a list of step indexes for each proof. The steps use the proof step
+// encoding with the hashes that can be computed from earlier proofs left out. A branch step
+// then holds only the remaining neighbor hashes, and a fork neighbor root or leaf neighbor
+// value is an empty byte string
+func (m *MultiProof) MarshalCBOR() ([]byte, error) {
+	tmpSteps, tmpProofs := m.table.encode()
+	tmpData := cbor.IndefLengthList{
+		tmpSteps,
+		tmpProofs,
+	}
+	return cbor.Encode(&tmpData)
+}
+
+func (m *MultiProof) UnmarshalCBOR(data []byte) error {
+	*m = MultiProof{
+		table: newProofTable(),
+	}
+	var fields []cbor.RawMessage
+	if err := decodeExact(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 2 {
+		return errors.New("multi-proof missing fields")
+	}
+	if err := m.table.decode(fields[0], fields[1]); err != nil {
+		return fmt.Errorf("invalid multi-proof: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMultiProofVerify(t *testing.T) {
+	trie := NewTrie()
+	var keys, values [][]byte
+	for i := range 200 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		value := fmt.Appendf(nil, "value-%d", i)
+		trie.Set(key, value)
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	multiProof, err := trie.ProveMany(keys)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating multi-proof: %s", err)
+	}
+	if multiProof.Len() != len(keys) {
+		t.Fatalf(
+			"did not get expected proof count: got %d, expected %d",
+			multiProof.Len(),
+			len(keys),
+		)
+	}
+	if !multiProof.Verify(trie.Hash(), keys, values) {
+		t.Fatal("multi-proof did not verify")
+	}
+	badValues := append([][]byte{}, values...)
+	badValues[17] = []byte("wrong value")
+	if multiProof.Verify(trie.Hash(), keys, badValues) {
+		t.Fatal("multi-proof verified with wrong value")
+	}
+	if multiProof.Verify(trie.Hash(), keys[1:], values[1:]) {
+		t.Fatal("multi-proof verified with missing key")
+	}
+	// Individual proofs should match the ones generated directly
+	proofs, err := multiProof.Proofs(keys, values)
+	if err != nil {
+		t.Fatalf("got unexpected error when extracting proofs: %s", err)
+	}
+	for idx, key := range keys {
+		proof := proofs[idx]
+		wantProof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		assertProofStepsEqual(t, proof, wantProof)
+	}
+}
+
+func TestMultiProofMarshalCbor(t *testing.T) {
+	trie := NewTrie()
+	var keys, values [][]byte
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+		keys = append(keys, []byte(entry.key))
+		values = append(values, []byte(entry.value))
+	}
+	multiProof, err := trie.ProveMany(keys)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating multi-proof: %s", err)
+	}
+	multiProofCbor, err := multiProof.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding multi-proof: %s", err)
+	}
+	// The combined encoding should be smaller than the individual proofs
+	var individualSize int
+	for _, key := range keys {
+		proof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		proofCbor, err := proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		individualSize += len(proofCbor)
+	}
+	if len(multiProofCbor) >= individualSize {
+		t.Fatalf(
+			"multi-proof is not smaller than individual proofs: got %d bytes, individual %d bytes",
+			len(multiProofCbor),
+			individualSize,
+		)
+	}
+	var decoded MultiProof
+	if err := decoded.UnmarshalCBOR(multiProofCbor); err != nil {
+		t.Fatalf("got unexpected error when decoding multi-proof: %s", err)
+	}
+	if !decoded.Verify(trie.Hash(), keys, values) {
+		t.Fatal("decoded multi-proof did not verify")
+	}
+	roundTripCbor, err := decoded.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when re-encoding multi-proof: %s", err)
+	}
+	if !bytes.Equal(roundTripCbor, multiProofCbor) {
+		t.Fatal("round-trip multi-proof CBOR mismatch")
+	}
+}
+
+func TestMultiProofMissingKey(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	_, err := trie.ProveMany([][]byte{[]byte("abcd"), []byte("bcde")})
+	if err != ErrKeyNotExist {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyNotExist)
+	}
+}
+
+func TestMultiProofUnmarshalRejectsBadStepIndex(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	trie.Set([]byte("bcde"), []byte("2"))
+	multiProof, err := trie.ProveMany([][]byte{[]byte("abcd")})
+	if err != nil {
+		t.Fatalf("got unexpected error when generating multi-proof: %s", err)
+	}
+	multiProof.table.proofs[0][0] = 5
+	multiProofCbor, err := multiProof.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding multi-proof: %s", err)
+	}
+	var decoded MultiProof
+	if err := decoded.UnmarshalCBOR(multiProofCbor); err == nil {
+		t.Fatal("expected step index error but got nil")
+	}
+}
+
+func TestMultiProofSize(t *testing.T) {
+	trie := NewTrie()
+	for i := range 1000 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	var keys, values [][]byte
+	for i := 0; i < 1000; i += 5 {
+		keys = append(keys, fmt.Appendf(nil, "key-%d", i))
+		values = append(values, fmt.Appendf(nil, "value-%d", i))
+	}
+	multiProof, err := trie.ProveMany(keys)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating multi-proof: %s", err)
+	}
+	if !multiProof.Verify(trie.Hash(), keys, values) {
+		t.Fatal("multi-proof did not verify")
+	}
+	multiProofCbor, err := multiProof.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding multi-proof: %s", err)
+	}
+	var individualSize int
+	for _, key := range keys {
+		proof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		proofCbor, err := proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		individualSize += len(proofCbor)
+	}
+	// Leaving out the hashes covering other proven keys should make the multi-proof much
+	// smaller than the individual proofs
+	if len(multiProofCbor)*2 >= individualSize {
+		t.Fatalf(
+			"multi-proof is not less than half the size of individual proofs: got %d bytes, individual %d bytes",
+			len(multiProofCbor),
+			individualSize,
+		)
+	}
+}
+
+func FuzzMultiProofUnmarshalCbor(f *testing.F) {
+	trie := NewTrie()
+	var keys, values [][]byte
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+		keys = append(keys, []byte(entry.key))
+		values = append(values, []byte(entry.value))
+	}
+	multiProof, err := trie.ProveMany(keys)
+	if err != nil {
+		f.Fatalf("got unexpected error when generating multi-proof: %s", err)
+	}
+	multiProofCbor, err := multiProof.MarshalCBOR()
+	if err != nil {
+		f.Fatalf("got unexpected error when encoding multi-proof: %s", err)
+	}
+	f.Add(multiProofCbor)
+	root := trie.Hash()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var tmpMultiProof MultiProof
+		if err := tmpMultiProof.UnmarshalCBOR(data); err != nil {
+			return
+		}
+		if tmpMultiProof.Len() != len(keys) {
+			return
+		}
+		// Anything that decodes should be safe to verify
+		tmpMultiProof.Verify(root, keys, values)
+	})
+}
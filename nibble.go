@@ -82,6 +82,16 @@ func individualBytesToNibbles(data []byte) ([]Nibble, error) {
 	return ret, nil
 }
 
+// validateNibbles returns an error if any of the Nibbles is outside of the valid range
+func validateNibbles(data []Nibble) error {
+	for i, n := range data {
+		if n > 0x0f {
+			return fmt.Errorf("nibble %d out of range: 0x%02x", i, byte(n))
+		}
+	}
+	return nil
+}
+
 // nibblesToHexString converts a series of Nibbles into a hex string representing those nibbles.
 func nibblesToHexString(data []Nibble) string {
 	var sb strings.Builder
@@ -18,7 +18,20 @@ type Node interface {
 	isNode()
 	Hash() Hash
 	String() string
-	generateProof([]Nibble) (*Proof, error)
+	generateProof([]Nibble, *proofCache) (*Proof, error)
+	generateExclusionProof([]Nibble, *proofCache) (*Proof, error)
+}
+
+// nodeCount returns the number of entries in the subtree under the node
+func nodeCount(node Node) int {
+	switch n := node.(type) {
+	case *Leaf:
+		return 1
+	case *Branch:
+		return n.count
+	default:
+		return 0
+	}
 }
 
 func merkleRoot(nodes []Node) Hash {
@@ -31,6 +44,11 @@ func merkleRoot(nodes []Node) Hash {
 		}
 		tmpHashes = append(tmpHashes, tmpHash)
 	}
+	return merkleRootHashes(tmpHashes)
+}
+
+// merkleRootHashes calculates the merkle root for a power-of-two sized list of hashes
+func merkleRootHashes(tmpHashes []Hash) Hash {
 	// Concat and hash child hashes in pairs, repeating until only a single hash remains
 	for len(tmpHashes) > 1 {
 		newTmpHashes := make([]Hash, 0, len(tmpHashes)/2)
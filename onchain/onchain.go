@@ -0,0 +1,438 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package onchain reimplements the on-chain logic of the Aiken merkle-patricia-forestry
+// library step by step over the Plutus data proof format. It deliberately shares no code
+// with the parent package, so that it can be used to check the proofs and hashes produced
+// there against the behavior of the validator
+package onchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const hashSize = 32
+
+var (
+	nullHash  = make([]byte, hashSize)
+	nullHash2 = combine(nullHash, nullHash)
+	nullHash4 = combine(nullHash2, nullHash2)
+	nullHash8 = combine(nullHash4, nullHash4)
+)
+
+// ErrRootMismatch is returned when a proof does not lead to the current root, which would
+// fail the validator
+var ErrRootMismatch = errors.New("proof does not match root")
+
+// Forestry is the on-chain trie value, which only holds the root hash
+type Forestry struct {
+	root []byte
+}
+
+// Empty returns a Forestry for an empty trie
+func Empty() *Forestry {
+	return &Forestry{root: bytes.Clone(nullHash)}
+}
+
+// FromRoot returns a Forestry with the specified root hash
+func FromRoot(root []byte) (*Forestry, error) {
+	if len(root) != hashSize {
+		return nil, fmt.Errorf("invalid root hash length: %d", len(root))
+	}
+	return &Forestry{root: bytes.Clone(root)}, nil
+}
+
+// Root returns the root hash
+func (f *Forestry) Root() []byte {
+	return bytes.Clone(f.root)
+}
+
+// IsEmpty returns whether the trie is empty
+func (f *Forestry) IsEmpty() bool {
+	return bytes.Equal(f.root, nullHash)
+}
+
+// Has returns whether the proof shows that the key and value are in the trie
+func (f *Forestry) Has(key []byte, value []byte, proof Proof) bool {
+	root, err := Including(key, value, proof)
+	return err == nil && bytes.Equal(root, f.root)
+}
+
+// Miss returns whether the proof shows that the key is not in the trie
+func (f *Forestry) Miss(key []byte, proof Proof) bool {
+	root, err := Excluding(key, proof)
+	return err == nil && bytes.Equal(root, f.root)
+}
+
+// Insert returns the trie with the key and value added, using a proof for the key in the
+// current trie
+func (f *Forestry) Insert(key []byte, value []byte, proof Proof) (*Forestry, error) {
+	if err := f.expectRoot(Excluding(key, proof)); err != nil {
+		return nil, err
+	}
+	return fromResult(Including(key, value, proof))
+}
+
+// Delete returns the trie with the key and value removed, using a proof for the key in the
+// current trie
+func (f *Forestry) Delete(key []byte, value []byte, proof Proof) (*Forestry, error) {
+	if err := f.expectRoot(Including(key, value, proof)); err != nil {
+		return nil, err
+	}
+	return fromResult(Excluding(key, proof))
+}
+
+// Update returns the trie with the value for the key changed, using a proof for the key in
+// the current trie
+func (f *Forestry) Update(
+	key []byte,
+	proof Proof,
+	oldValue []byte,
+	newValue []byte,
+) (*Forestry, error) {
+	if err := f.expectRoot(Including(key, oldValue, proof)); err != nil {
+		return nil, err
+	}
+	return fromResult(Including(key, newValue, proof))
+}
+
+func (f *Forestry) expectRoot(root []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, f.root) {
+		return ErrRootMismatch
+	}
+	return nil
+}
+
+func fromResult(root []byte, err error) (*Forestry, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &Forestry{root: root}, nil
+}
+
+// Including returns the root of the trie described by the proof with the key and value in it
+func Including(key []byte, value []byte, proof Proof) ([]byte, error) {
+	return doIncluding(blake2b256(key), blake2b256(value), 0, proof)
+}
+
+func doIncluding(path []byte, value []byte, cursor int64, proof Proof) ([]byte, error) {
+	if len(proof) == 0 {
+		return combine(suffix(path, cursor), value), nil
+	}
+	switch step := proof[0].(type) {
+	case Branch:
+		nextCursor := cursor + 1 + step.Skip
+		root, err := doIncluding(path, value, nextCursor, proof[1:])
+		if err != nil {
+			return nil, err
+		}
+		return doBranch(path, cursor, nextCursor, root, step.Neighbors)
+	case Fork:
+		nextCursor := cursor + 1 + step.Skip
+		root, err := doIncluding(path, value, nextCursor, proof[1:])
+		if err != nil {
+			return nil, err
+		}
+		return doFork(path, cursor, nextCursor, root, step.Neighbor)
+	case Leaf:
+		nextCursor := cursor + 1 + step.Skip
+		root, err := doIncluding(path, value, nextCursor, proof[1:])
+		if err != nil {
+			return nil, err
+		}
+		neighbor, err := leafNeighbor(step, nextCursor)
+		if err != nil {
+			return nil, err
+		}
+		return doFork(path, cursor, nextCursor, root, neighbor)
+	default:
+		return nil, fmt.Errorf("unknown proof step: %T", step)
+	}
+}
+
+// Excluding returns the root of the trie described by the proof without the key in it
+func Excluding(key []byte, proof Proof) ([]byte, error) {
+	return doExcluding(blake2b256(key), 0, proof)
+}
+
+func doExcluding(path []byte, cursor int64, proof Proof) ([]byte, error) {
+	if len(proof) == 0 {
+		return bytes.Clone(nullHash), nil
+	}
+	switch step := proof[0].(type) {
+	case Branch:
+		nextCursor := cursor + 1 + step.Skip
+		root, err := doExcluding(path, nextCursor, proof[1:])
+		if err != nil {
+			return nil, err
+		}
+		return doBranch(path, cursor, nextCursor, root, step.Neighbors)
+	case Fork:
+		if len(proof) == 1 {
+			prefix, err := nibbles(path, cursor, cursor+step.Skip)
+			if err != nil {
+				return nil, err
+			}
+			prefix = append(prefix, byte(step.Neighbor.Nibble))
+			prefix = append(prefix, step.Neighbor.Prefix...)
+			return combine(prefix, step.Neighbor.Root), nil
+		}
+		nextCursor := cursor + 1 + step.Skip
+		root, err := doExcluding(path, nextCursor, proof[1:])
+		if err != nil {
+			return nil, err
+		}
+		return doFork(path, cursor, nextCursor, root, step.Neighbor)
+	case Leaf:
+		if len(proof) == 1 {
+			return combine(suffix(step.Key, cursor), step.Value), nil
+		}
+		nextCursor := cursor + 1 + step.Skip
+		root, err := doExcluding(path, nextCursor, proof[1:])
+		if err != nil {
+			return nil, err
+		}
+		neighbor, err := leafNeighbor(step, nextCursor)
+		if err != nil {
+			return nil, err
+		}
+		return doFork(path, cursor, nextCursor, root, neighbor)
+	default:
+		return nil, fmt.Errorf("unknown proof step: %T", step)
+	}
+}
+
+func leafNeighbor(step Leaf, nextCursor int64) (Neighbor, error) {
+	neighborNibble, err := nibble(step.Key, nextCursor-1)
+	if err != nil {
+		return Neighbor{}, err
+	}
+	return Neighbor{
+		Nibble: int64(neighborNibble),
+		Prefix: suffix(step.Key, nextCursor),
+		Root:   step.Value,
+	}, nil
+}
+
+func doBranch(
+	path []byte,
+	cursor int64,
+	nextCursor int64,
+	root []byte,
+	neighbors []byte,
+) ([]byte, error) {
+	branch, err := nibble(path, nextCursor-1)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := nibbles(path, cursor, nextCursor-1)
+	if err != nil {
+		return nil, err
+	}
+	return combine(
+		prefix,
+		merkle16(
+			int64(branch),
+			root,
+			slice(neighbors, 0, hashSize),
+			slice(neighbors, hashSize, hashSize),
+			slice(neighbors, 2*hashSize, hashSize),
+			slice(neighbors, 3*hashSize, hashSize),
+		),
+	), nil
+}
+
+func doFork(
+	path []byte,
+	cursor int64,
+	nextCursor int64,
+	root []byte,
+	neighbor Neighbor,
+) ([]byte, error) {
+	branch, err := nibble(path, nextCursor-1)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := nibbles(path, cursor, nextCursor-1)
+	if err != nil {
+		return nil, err
+	}
+	if int64(branch) == neighbor.Nibble {
+		return nil, errors.New("fork neighbor nibble matches path")
+	}
+	if neighbor.Nibble < 0 || neighbor.Nibble > 0xf {
+		return nil, fmt.Errorf("fork neighbor nibble out of range: %d", neighbor.Nibble)
+	}
+	return combine(
+		prefix,
+		sparseMerkle16(
+			int64(branch),
+			root,
+			neighbor.Nibble,
+			combine(neighbor.Prefix, neighbor.Root),
+		),
+	), nil
+}
+
+func blake2b256(data []byte) []byte {
+	ret := blake2b.Sum256(data)
+	return ret[:]
+}
+
+func combine(left []byte, right []byte) []byte {
+	tmpData := make([]byte, 0, len(left)+len(right))
+	tmpData = append(tmpData, left...)
+	tmpData = append(tmpData, right...)
+	return blake2b256(tmpData)
+}
+
+// slice returns up to length bytes starting at the specified offset, clamped to the data like
+// the sliceByteString builtin
+func slice(data []byte, start int, length int) []byte {
+	start = min(start, len(data))
+	end := min(start+length, len(data))
+	return data[start:end]
+}
+
+// nibble returns the nibble at the specified index of the path
+func nibble(path []byte, index int64) (byte, error) {
+	if index < 0 || index/2 >= int64(len(path)) {
+		return 0, fmt.Errorf("nibble index out of range: %d", index)
+	}
+	tmpByte := path[index/2]
+	if index%2 == 0 {
+		return tmpByte >> 4, nil
+	}
+	return tmpByte & 0x0f, nil
+}
+
+// nibbles returns the nibbles of the path between start and end with one byte per nibble
+func nibbles(path []byte, start int64, end int64) ([]byte, error) {
+	var ret []byte
+	for i := start; i < end; i++ {
+		tmpNibble, err := nibble(path, i)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, tmpNibble)
+	}
+	return ret, nil
+}
+
+// suffix returns the encoding of the remainder of the path from the cursor used when hashing
+// a leaf. An even cursor is marked with 0xff, while an odd cursor is marked with 0x00 and
+// the first nibble
+func suffix(path []byte, cursor int64) []byte {
+	if cursor%2 == 0 {
+		return append([]byte{0xff}, slice(path, int(cursor/2), len(path))...)
+	}
+	// The cursor is within the path for any proof that passed the nibble checks
+	firstNibble, _ := nibble(path, cursor)
+	return append(
+		[]byte{0x00, firstNibble},
+		slice(path, int((cursor+1)/2), len(path))...,
+	)
+}
+
+func merkle16(branch int64, root, neighbor8, neighbor4, neighbor2, neighbor1 []byte) []byte {
+	if branch <= 7 {
+		return combine(merkle8(branch, root, neighbor4, neighbor2, neighbor1), neighbor8)
+	}
+	return combine(neighbor8, merkle8(branch-8, root, neighbor4, neighbor2, neighbor1))
+}
+
+func merkle8(branch int64, root, neighbor4, neighbor2, neighbor1 []byte) []byte {
+	if branch <= 3 {
+		return combine(merkle4(branch, root, neighbor2, neighbor1), neighbor4)
+	}
+	return combine(neighbor4, merkle4(branch-4, root, neighbor2, neighbor1))
+}
+
+func merkle4(branch int64, root, neighbor2, neighbor1 []byte) []byte {
+	if branch <= 1 {
+		return combine(merkle2(branch, root, neighbor1), neighbor2)
+	}
+	return combine(neighbor2, merkle2(branch-2, root, neighbor1))
+}
+
+func merkle2(branch int64, root, neighbor []byte) []byte {
+	if branch <= 0 {
+		return combine(root, neighbor)
+	}
+	return combine(neighbor, root)
+}
+
+func sparseMerkle16(me int64, meHash []byte, neighbor int64, neighborHash []byte) []byte {
+	if me < 8 {
+		if neighbor < 8 {
+			return combine(sparseMerkle8(me, meHash, neighbor, neighborHash), nullHash8)
+		}
+		return combine(
+			merkle8(me, meHash, nullHash4, nullHash2, nullHash),
+			merkle8(neighbor-8, neighborHash, nullHash4, nullHash2, nullHash),
+		)
+	}
+	if neighbor >= 8 {
+		return combine(nullHash8, sparseMerkle8(me-8, meHash, neighbor-8, neighborHash))
+	}
+	return combine(
+		merkle8(neighbor, neighborHash, nullHash4, nullHash2, nullHash),
+		merkle8(me-8, meHash, nullHash4, nullHash2, nullHash),
+	)
+}
+
+func sparseMerkle8(me int64, meHash []byte, neighbor int64, neighborHash []byte) []byte {
+	if me < 4 {
+		if neighbor < 4 {
+			return combine(sparseMerkle4(me, meHash, neighbor, neighborHash), nullHash4)
+		}
+		return combine(
+			merkle4(me, meHash, nullHash2, nullHash),
+			merkle4(neighbor-4, neighborHash, nullHash2, nullHash),
+		)
+	}
+	if neighbor >= 4 {
+		return combine(nullHash4, sparseMerkle4(me-4, meHash, neighbor-4, neighborHash))
+	}
+	return combine(
+		merkle4(neighbor, neighborHash, nullHash2, nullHash),
+		merkle4(me-4, meHash, nullHash2, nullHash),
+	)
+}
+
+func sparseMerkle4(me int64, meHash []byte, neighbor int64, neighborHash []byte) []byte {
+	if me < 2 {
+		if neighbor < 2 {
+			return combine(merkle2(me, meHash, neighborHash), nullHash2)
+		}
+		return combine(
+			merkle2(me, meHash, nullHash),
+			merkle2(neighbor-2, neighborHash, nullHash),
+		)
+	}
+	if neighbor >= 2 {
+		return combine(nullHash2, merkle2(me-2, meHash, neighborHash))
+	}
+	return combine(
+		merkle2(neighbor, neighborHash, nullHash),
+		merkle2(me-2, meHash, nullHash),
+	)
+}
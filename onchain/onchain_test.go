@@ -0,0 +1,184 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	mpf "github.com/blinklabs-io/merkle-patricia-forestry"
+)
+
+// decodeTrieProof encodes a proof from the parent package and decodes it as Plutus data
+func decodeTrieProof(t *testing.T, proof *mpf.Proof, encoding mpf.CBOREncoding) Proof {
+	t.Helper()
+	proofCbor, err := proof.MarshalCBORWithOptions(encoding)
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof: %s", err)
+	}
+	ret, err := DecodeProof(proofCbor)
+	if err != nil {
+		t.Fatalf("got unexpected error when decoding proof: %s", err)
+	}
+	return ret
+}
+
+func TestTrieProofsHas(t *testing.T) {
+	trie := mpf.NewTrie()
+	keys := []string{
+		"apple[uid: 58]",
+		"banana[uid: 218]",
+		"fig[uid: 68267]",
+		"pineapple[uid: 12577]",
+		"tomato[uid: 83468]",
+	}
+	for i, key := range keys {
+		trie.Set([]byte(key), fmt.Appendf(nil, "value-%d", i))
+	}
+	for i := range 100 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	forestry, err := FromRoot(trie.Hash().Bytes())
+	if err != nil {
+		t.Fatalf("got unexpected error when creating forestry: %s", err)
+	}
+	for i, key := range keys {
+		proof, err := trie.Prove([]byte(key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		for _, encoding := range []mpf.CBOREncoding{mpf.EncodeIndefinite, mpf.EncodeDefinite} {
+			onchainProof := decodeTrieProof(t, proof, encoding)
+			if !forestry.Has([]byte(key), fmt.Appendf(nil, "value-%d", i), onchainProof) {
+				t.Fatalf("proof did not verify on-chain for key %q", key)
+			}
+			if forestry.Has([]byte(key), []byte("wrong value"), onchainProof) {
+				t.Fatalf("proof verified on-chain with wrong value for key %q", key)
+			}
+		}
+	}
+}
+
+func TestRandomOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	trie := mpf.NewTrie()
+	forestry := Empty()
+	values := make(map[string][]byte)
+	var keys []string
+	nextKey := 0
+	for i := range 500 {
+		value := fmt.Appendf(nil, "value-%d", i)
+		switch {
+		case len(keys) == 0 || rng.Intn(2) == 0:
+			key := fmt.Appendf(nil, "key-%d", nextKey)
+			nextKey++
+			// The key should be missing beforehand
+			absenceProof, err := trie.ProveAbsence(key)
+			if err != nil {
+				t.Fatalf("got unexpected error when generating exclusion proof: %s", err)
+			}
+			if !forestry.Miss(key, decodeTrieProof(t, absenceProof, mpf.EncodeIndefinite)) {
+				t.Fatalf("exclusion proof did not verify on-chain for key %q", key)
+			}
+			trans, err := trie.InsertWithProof(key, value)
+			if err != nil {
+				t.Fatalf("got unexpected error when inserting key: %s", err)
+			}
+			forestry, err = forestry.Insert(
+				key,
+				value,
+				decodeTrieProof(t, trans.Proof, mpf.EncodeIndefinite),
+			)
+			if err != nil {
+				t.Fatalf("got unexpected error on-chain when inserting key %q: %s", key, err)
+			}
+			keys = append(keys, string(key))
+			values[string(key)] = value
+		case rng.Intn(2) == 0:
+			key := keys[rng.Intn(len(keys))]
+			trans, err := trie.UpdateWithProof([]byte(key), value)
+			if err != nil {
+				t.Fatalf("got unexpected error when updating key: %s", err)
+			}
+			forestry, err = forestry.Update(
+				[]byte(key),
+				decodeTrieProof(t, trans.Proof, mpf.EncodeDefinite),
+				values[key],
+				value,
+			)
+			if err != nil {
+				t.Fatalf("got unexpected error on-chain when updating key %q: %s", key, err)
+			}
+			values[key] = value
+		default:
+			keyIdx := rng.Intn(len(keys))
+			key := keys[keyIdx]
+			trans, err := trie.DeleteWithProof([]byte(key))
+			if err != nil {
+				t.Fatalf("got unexpected error when deleting key: %s", err)
+			}
+			forestry, err = forestry.Delete(
+				[]byte(key),
+				values[key],
+				decodeTrieProof(t, trans.Proof, mpf.EncodeIndefinite),
+			)
+			if err != nil {
+				t.Fatalf("got unexpected error on-chain when deleting key %q: %s", key, err)
+			}
+			keys = append(keys[:keyIdx], keys[keyIdx+1:]...)
+			delete(values, key)
+		}
+		if !bytes.Equal(forestry.Root(), trie.Hash().Bytes()) {
+			t.Fatalf(
+				"on-chain root does not match trie after operation %d\n  got:    %x\n  wanted: %s",
+				i,
+				forestry.Root(),
+				trie.Hash().String(),
+			)
+		}
+	}
+}
+
+func TestInsertRejectsWrongRoot(t *testing.T) {
+	trie := mpf.NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	trans, err := trie.InsertWithProof([]byte("bcde"), []byte("2"))
+	if err != nil {
+		t.Fatalf("got unexpected error when inserting key: %s", err)
+	}
+	proof := decodeTrieProof(t, trans.Proof, mpf.EncodeIndefinite)
+	if _, err := Empty().Insert([]byte("bcde"), []byte("2"), proof); !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrRootMismatch)
+	}
+}
+
+func TestDecodeProofErrors(t *testing.T) {
+	testDefs := [][]byte{
+		// Not a list
+		{0x01},
+		// Unknown constructor
+		{0x9f, 0xd8, 0x7c, 0x9f, 0x00, 0xff, 0xff},
+		// Branch missing neighbors
+		{0x9f, 0xd8, 0x79, 0x9f, 0x00, 0xff, 0xff},
+	}
+	for _, testDef := range testDefs {
+		if _, err := DecodeProof(testDef); err == nil {
+			t.Fatalf("expected error decoding %x but got nil", testDef)
+		}
+	}
+}
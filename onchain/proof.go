@@ -0,0 +1,174 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// Proof is the list of proof steps, ordered from the root of the trie
+type Proof []ProofStep
+
+// ProofStep is one of Branch, Fork or Leaf
+type ProofStep interface {
+	isProofStep()
+}
+
+// Branch is a proof step for a branch with more than one neighbor. The neighbors are the
+// concatenated merkle proof hashes for the children of the branch
+type Branch struct {
+	Skip      int64
+	Neighbors []byte
+}
+
+// Fork is a proof step for a branch whose only neighbor is another branch
+type Fork struct {
+	Skip     int64
+	Neighbor Neighbor
+}
+
+// Leaf is a proof step for a branch whose only neighbor is a leaf. The key is the full key
+// path and the value is the value hash of the neighbor
+type Leaf struct {
+	Skip  int64
+	Key   []byte
+	Value []byte
+}
+
+// Neighbor is the branch neighbor of a Fork step. The prefix has one byte per nibble
+type Neighbor struct {
+	Nibble int64
+	Prefix []byte
+	Root   []byte
+}
+
+func (Branch) isProofStep() {}
+func (Fork) isProofStep()   {}
+func (Leaf) isProofStep()   {}
+
+// DecodeProof decodes a proof from its Plutus data CBOR encoding
+func DecodeProof(data []byte) (Proof, error) {
+	var tmpSteps []cbor.RawMessage
+	if err := decodeExact(data, &tmpSteps); err != nil {
+		return nil, err
+	}
+	ret := make(Proof, 0, len(tmpSteps))
+	for idx, tmpStep := range tmpSteps {
+		step, err := decodeProofStep(tmpStep)
+		if err != nil {
+			return nil, fmt.Errorf("proof step %d: %w", idx, err)
+		}
+		ret = append(ret, step)
+	}
+	return ret, nil
+}
+
+func decodeProofStep(data []byte) (ProofStep, error) {
+	var constructor cbor.ConstructorDecoder
+	if err := decodeExact(data, &constructor); err != nil {
+		return nil, err
+	}
+	var fields []cbor.RawMessage
+	if err := constructor.DecodeFields(&fields); err != nil {
+		return nil, err
+	}
+	switch constructor.Tag() {
+	case 0:
+		if len(fields) != 2 {
+			return nil, errors.New("branch: unexpected field count")
+		}
+		var ret Branch
+		if err := decodeExact(fields[0], &ret.Skip); err != nil {
+			return nil, fmt.Errorf("branch: invalid skip: %w", err)
+		}
+		if err := decodeExact(fields[1], &ret.Neighbors); err != nil {
+			return nil, fmt.Errorf("branch: invalid neighbors: %w", err)
+		}
+		return ret, nil
+	case 1:
+		if len(fields) != 2 {
+			return nil, errors.New("fork: unexpected field count")
+		}
+		var ret Fork
+		if err := decodeExact(fields[0], &ret.Skip); err != nil {
+			return nil, fmt.Errorf("fork: invalid skip: %w", err)
+		}
+		neighbor, err := decodeNeighbor(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("fork: %w", err)
+		}
+		ret.Neighbor = neighbor
+		return ret, nil
+	case 2:
+		if len(fields) != 3 {
+			return nil, errors.New("leaf: unexpected field count")
+		}
+		var ret Leaf
+		if err := decodeExact(fields[0], &ret.Skip); err != nil {
+			return nil, fmt.Errorf("leaf: invalid skip: %w", err)
+		}
+		if err := decodeExact(fields[1], &ret.Key); err != nil {
+			return nil, fmt.Errorf("leaf: invalid key: %w", err)
+		}
+		if err := decodeExact(fields[2], &ret.Value); err != nil {
+			return nil, fmt.Errorf("leaf: invalid value: %w", err)
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("unknown proof step constructor: %d", constructor.Tag())
+	}
+}
+
+func decodeNeighbor(data []byte) (Neighbor, error) {
+	var ret Neighbor
+	var constructor cbor.ConstructorDecoder
+	if err := decodeExact(data, &constructor); err != nil {
+		return ret, fmt.Errorf("invalid neighbor: %w", err)
+	}
+	if constructor.Tag() != 0 {
+		return ret, fmt.Errorf("unknown neighbor constructor: %d", constructor.Tag())
+	}
+	var fields []cbor.RawMessage
+	if err := constructor.DecodeFields(&fields); err != nil {
+		return ret, fmt.Errorf("invalid neighbor: %w", err)
+	}
+	if len(fields) != 3 {
+		return ret, errors.New("neighbor: unexpected field count")
+	}
+	if err := decodeExact(fields[0], &ret.Nibble); err != nil {
+		return ret, fmt.Errorf("neighbor: invalid nibble: %w", err)
+	}
+	if err := decodeExact(fields[1], &ret.Prefix); err != nil {
+		return ret, fmt.Errorf("neighbor: invalid prefix: %w", err)
+	}
+	if err := decodeExact(fields[2], &ret.Root); err != nil {
+		return ret, fmt.Errorf("neighbor: invalid root: %w", err)
+	}
+	return ret, nil
+}
+
+func decodeExact(data []byte, dest any) error {
+	bytesRead, err := cbor.Decode(data, dest)
+	if err != nil {
+		return err
+	}
+	if bytesRead != len(data) {
+		return fmt.Errorf("trailing data: %d bytes", len(data)-bytesRead)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+type OperationType int
+
+const (
+	OperationInsert OperationType = 1
+	OperationUpdate OperationType = 2
+	OperationDelete OperationType = 3
+)
+
+func (o OperationType) String() string {
+	switch o {
+	case OperationInsert:
+		return "insert"
+	case OperationUpdate:
+		return "update"
+	case OperationDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Operation describes a single change to a trie. The value is the new value for an insert or
+// update and is ignored for a delete
+type Operation struct {
+	Type  OperationType
+	Key   []byte
+	Value []byte
+}
@@ -43,6 +43,24 @@ func (p ProofStepType) String() string {
 	}
 }
 
+// CBOREncoding controls how lists are encoded in the CBOR representation of a proof
+type CBOREncoding int
+
+const (
+	// EncodeIndefinite uses indefinite-length lists, matching the JS and Aiken libraries
+	EncodeIndefinite CBOREncoding = iota
+	// EncodeDefinite uses definite-length lists, as produced by canonical Plutus data encoders
+	EncodeDefinite
+)
+
+// list returns the specified items as a list value using the encoding
+func (e CBOREncoding) list(items ...any) any {
+	if e == EncodeDefinite {
+		return items
+	}
+	return cbor.IndefLengthList(items)
+}
+
 type Proof struct {
 	path  []Nibble
 	value []byte
@@ -60,7 +78,31 @@ func newProof(path []Nibble, value []byte) *Proof {
 	return p
 }
 
+// NewProof returns a proof made up of the specified steps, ordered from the root of the trie
+func NewProof(steps ...ProofStep) *Proof {
+	p := &Proof{}
+	for _, step := range steps {
+		p.steps = append(p.steps, step.clone())
+	}
+	return p
+}
+
+// Steps returns the steps that make up the proof, ordered from the root of the trie
+func (p *Proof) Steps() []ProofStep {
+	ret := make([]ProofStep, 0, len(p.steps))
+	for _, step := range p.steps {
+		ret = append(ret, step.clone())
+	}
+	return ret
+}
+
 func (p *Proof) Rewind(targetIdx int, prefixLen int, neighbors []Node) {
+	p.steps = slices.Insert(p.steps, 0, rewindStep(targetIdx, prefixLen, neighbors))
+}
+
+// rewindStep returns the proof step for a branch with the specified prefix length and
+// children, where the path being proven goes through the child at the target index
+func rewindStep(targetIdx int, prefixLen int, neighbors []Node) ProofStep {
 	nonEmptyNeighbors := []Node{}
 	var nonEmptyNeighborIdx int
 	for idx, neighbor := range neighbors {
@@ -77,7 +119,7 @@ func (p *Proof) Rewind(targetIdx int, prefixLen int, neighbors []Node) {
 		neighbor := nonEmptyNeighbors[0]
 		switch n := neighbor.(type) {
 		case *Leaf:
-			step := ProofStep{
+			return ProofStep{
 				stepType:     ProofStepTypeLeaf,
 				prefixLength: prefixLen,
 				neighbor: ProofStepNeighbor{
@@ -85,9 +127,8 @@ func (p *Proof) Rewind(targetIdx int, prefixLen int, neighbors []Node) {
 					value: HashValue(n.value),
 				},
 			}
-			p.steps = slices.Insert(p.steps, 0, step)
 		case *Branch:
-			step := ProofStep{
+			return ProofStep{
 				stepType:     ProofStepTypeFork,
 				prefixLength: prefixLen,
 				neighbor: ProofStepNeighbor{
@@ -96,7 +137,6 @@ func (p *Proof) Rewind(targetIdx int, prefixLen int, neighbors []Node) {
 					root:   merkleRoot(n.children[:]),
 				},
 			}
-			p.steps = slices.Insert(p.steps, 0, step)
 		default:
 			panic(
 				fmt.Sprintf(
@@ -105,28 +145,49 @@ func (p *Proof) Rewind(targetIdx int, prefixLen int, neighbors []Node) {
 				),
 			)
 		}
-	} else {
-		step := ProofStep{
-			stepType:     ProofStepTypeBranch,
-			prefixLength: prefixLen,
-			neighbors:    merkleProof(neighbors, targetIdx),
-		}
-		p.steps = slices.Insert(p.steps, 0, step)
+	}
+	return ProofStep{
+		stepType:     ProofStepTypeBranch,
+		prefixLength: prefixLen,
+		neighbors:    merkleProof(neighbors, targetIdx),
 	}
 }
 
 func (p *Proof) MarshalCBOR() ([]byte, error) {
+	return p.MarshalCBORWithOptions(EncodeIndefinite)
+}
+
+// MarshalCBORWithOptions returns the CBOR encoding of the proof using the specified list encoding
+func (p *Proof) MarshalCBORWithOptions(encoding CBOREncoding) ([]byte, error) {
 	tmpSteps := make([]any, 0, len(p.steps))
 	for _, step := range p.steps {
-		tmpSteps = append(tmpSteps, &step)
+		stepCbor, err := step.MarshalCBORWithOptions(encoding)
+		if err != nil {
+			return nil, err
+		}
+		tmpSteps = append(tmpSteps, cbor.RawMessage(stepCbor))
 	}
-	tmpData := cbor.IndefLengthList(tmpSteps)
+	tmpData := encoding.list(tmpSteps...)
 	return cbor.Encode(&tmpData)
 }
 
+// CBORSizeDifference returns the number of bytes saved by encoding the proof with
+// EncodeDefinite rather than the default EncodeIndefinite
+func (p *Proof) CBORSizeDifference() (int, error) {
+	indefCbor, err := p.MarshalCBORWithOptions(EncodeIndefinite)
+	if err != nil {
+		return 0, err
+	}
+	defCbor, err := p.MarshalCBORWithOptions(EncodeDefinite)
+	if err != nil {
+		return 0, err
+	}
+	return len(indefCbor) - len(defCbor), nil
+}
+
 func (p *Proof) UnmarshalCBOR(data []byte) error {
 	*p = Proof{}
-	var tmpSteps []ProofStep
+	var tmpSteps []cbor.RawMessage
 	bytesRead, err := cbor.Decode(data, &tmpSteps)
 	if err != nil {
 		return err
@@ -137,7 +198,13 @@ func (p *Proof) UnmarshalCBOR(data []byte) error {
 			len(data)-bytesRead,
 		)
 	}
-	p.steps = tmpSteps
+	steps := make([]ProofStep, len(tmpSteps))
+	for i, tmpStep := range tmpSteps {
+		if err := steps[i].UnmarshalCBOR(tmpStep); err != nil {
+			return stepError(i, err)
+		}
+	}
+	p.steps = steps
 	return nil
 }
 
@@ -148,6 +215,112 @@ type ProofStep struct {
 	neighbor     ProofStepNeighbor
 }
 
+// NewBranchStep returns a proof step for a branch with more than one neighbor. The neighbors
+// are the merkle proof hashes for the children of the branch, as produced for the on-chain
+// Branch step
+func NewBranchStep(prefixLength int, neighbors []Hash) (ProofStep, error) {
+	if prefixLength < 0 {
+		return ProofStep{}, fmt.Errorf("negative prefix length: %d", prefixLength)
+	}
+	if len(neighbors) != branchProofNeighborCount {
+		return ProofStep{}, fmt.Errorf(
+			"incorrect branch neighbor count: got %d, want %d",
+			len(neighbors),
+			branchProofNeighborCount,
+		)
+	}
+	return ProofStep{
+		stepType:     ProofStepTypeBranch,
+		prefixLength: prefixLength,
+		neighbors:    slices.Clone(neighbors),
+	}, nil
+}
+
+// NewForkStep returns a proof step for a branch whose only neighbor is another branch, which
+// sits in the child slot for the specified nibble and has the specified prefix and merkle root
+// of children
+func NewForkStep(
+	prefixLength int,
+	nibble Nibble,
+	prefix []Nibble,
+	root Hash,
+) (ProofStep, error) {
+	if prefixLength < 0 {
+		return ProofStep{}, fmt.Errorf("negative prefix length: %d", prefixLength)
+	}
+	if err := validateNibbles([]Nibble{nibble}); err != nil {
+		return ProofStep{}, fmt.Errorf("invalid fork neighbor index: %w", err)
+	}
+	if err := validateNibbles(prefix); err != nil {
+		return ProofStep{}, fmt.Errorf("invalid fork neighbor prefix: %w", err)
+	}
+	return ProofStep{
+		stepType:     ProofStepTypeFork,
+		prefixLength: prefixLength,
+		neighbor: ProofStepNeighbor{
+			prefix: slices.Clone(prefix),
+			nibble: nibble,
+			root:   root,
+		},
+	}, nil
+}
+
+// NewLeafStep returns a proof step for a branch whose only neighbor is a leaf with the
+// specified key path and value hash
+func NewLeafStep(prefixLength int, key []Nibble, value Hash) (ProofStep, error) {
+	if prefixLength < 0 {
+		return ProofStep{}, fmt.Errorf("negative prefix length: %d", prefixLength)
+	}
+	if len(key) != HashSize*2 {
+		return ProofStep{}, fmt.Errorf(
+			"incorrect leaf neighbor key length: got %d, want %d",
+			len(key),
+			HashSize*2,
+		)
+	}
+	if err := validateNibbles(key); err != nil {
+		return ProofStep{}, fmt.Errorf("invalid leaf neighbor key: %w", err)
+	}
+	return ProofStep{
+		stepType:     ProofStepTypeLeaf,
+		prefixLength: prefixLength,
+		neighbor: ProofStepNeighbor{
+			key:   slices.Clone(key),
+			value: value,
+		},
+	}, nil
+}
+
+// clone returns a copy of the proof step which shares no memory with the original
+func (s *ProofStep) clone() ProofStep {
+	ret := *s
+	ret.neighbors = slices.Clone(s.neighbors)
+	ret.neighbor.key = slices.Clone(s.neighbor.key)
+	ret.neighbor.prefix = slices.Clone(s.neighbor.prefix)
+	return ret
+}
+
+// Type returns the type of the proof step
+func (s *ProofStep) Type() ProofStepType {
+	return s.stepType
+}
+
+// PrefixLength returns the number of nibbles in the prefix of the branch described by the
+// proof step
+func (s *ProofStep) PrefixLength() int {
+	return s.prefixLength
+}
+
+// Neighbors returns the merkle proof hashes for a branch proof step
+func (s *ProofStep) Neighbors() []Hash {
+	return slices.Clone(s.neighbors)
+}
+
+// Neighbor returns the only neighbor for a fork or leaf proof step
+func (s *ProofStep) Neighbor() ProofStepNeighbor {
+	return s.neighbor
+}
+
 func (s *ProofStep) UnmarshalCBOR(data []byte) error {
 	*s = ProofStep{}
 	var constructor cbor.ConstructorDecoder
@@ -181,6 +354,12 @@ func (s *ProofStep) UnmarshalCBOR(data []byte) error {
 }
 
 func (s *ProofStep) MarshalCBOR() ([]byte, error) {
+	return s.MarshalCBORWithOptions(EncodeIndefinite)
+}
+
+// MarshalCBORWithOptions returns the CBOR encoding of the proof step using the specified list
+// encoding
+func (s *ProofStep) MarshalCBORWithOptions(encoding CBOREncoding) ([]byte, error) {
 	switch s.stepType {
 	case ProofStepTypeBranch:
 		tmpNeighbors := make([]byte, 0, len(s.neighbors)*HashSize)
@@ -189,13 +368,13 @@ func (s *ProofStep) MarshalCBOR() ([]byte, error) {
 		}
 		tmpData := cbor.NewConstructorEncoder(
 			0,
-			cbor.IndefLengthList{
+			encoding.list(
 				s.prefixLength,
 				cbor.IndefLengthByteString{
 					tmpNeighbors[0:64],
 					tmpNeighbors[64:],
 				},
-			},
+			),
 		)
 		return cbor.Encode(tmpData)
 
@@ -203,28 +382,28 @@ func (s *ProofStep) MarshalCBOR() ([]byte, error) {
 		prefixBytes := nibblesToIndividualBytes(s.neighbor.prefix)
 		tmpData := cbor.NewConstructorEncoder(
 			1,
-			cbor.IndefLengthList{
+			encoding.list(
 				s.prefixLength,
 				cbor.NewConstructorEncoder(
 					0,
-					cbor.IndefLengthList{
+					encoding.list(
 						int(s.neighbor.nibble),
 						prefixBytes,
 						s.neighbor.root,
-					},
+					),
 				),
-			},
+			),
 		)
 		return cbor.Encode(tmpData)
 
 	case ProofStepTypeLeaf:
 		tmpData := cbor.NewConstructorEncoder(
 			2,
-			cbor.IndefLengthList{
+			encoding.list(
 				s.prefixLength,
 				nibblesToBytes(s.neighbor.key),
 				s.neighbor.value,
-			},
+			),
 		)
 		return cbor.Encode(tmpData)
 
@@ -241,6 +420,31 @@ type ProofStepNeighbor struct {
 	root   Hash
 }
 
+// Key returns the key path for a leaf neighbor
+func (n ProofStepNeighbor) Key() []Nibble {
+	return slices.Clone(n.key)
+}
+
+// Value returns the value hash for a leaf neighbor
+func (n ProofStepNeighbor) Value() Hash {
+	return n.value
+}
+
+// Prefix returns the prefix for a fork neighbor
+func (n ProofStepNeighbor) Prefix() []Nibble {
+	return slices.Clone(n.prefix)
+}
+
+// Nibble returns the child slot for a fork neighbor
+func (n ProofStepNeighbor) Nibble() Nibble {
+	return n.nibble
+}
+
+// Root returns the merkle root of the children for a fork neighbor
+func (n ProofStepNeighbor) Root() Hash {
+	return n.root
+}
+
 const branchProofNeighborCount = 4
 
 func (s *ProofStep) unmarshalBranchStep(
@@ -255,19 +459,22 @@ func (s *ProofStep) unmarshalBranchStep(
 	}
 	prefixLen, err := decodeNonNegativeInt(fields[0])
 	if err != nil {
-		return fmt.Errorf("invalid prefix length: %w", err)
+		return &stepFieldError{"prefixLength", fmt.Errorf("invalid prefix length: %w", err)}
 	}
 	neighborsBytes, err := decodeBytes(fields[1])
 	if err != nil {
-		return fmt.Errorf("invalid neighbors: %w", err)
+		return &stepFieldError{"neighbors", fmt.Errorf("invalid neighbors: %w", err)}
 	}
 	expectedNeighborBytes := branchProofNeighborCount * HashSize
 	if len(neighborsBytes) != expectedNeighborBytes {
-		return fmt.Errorf(
-			"incorrect branch neighbor data length: got %d, want %d",
-			len(neighborsBytes),
-			expectedNeighborBytes,
-		)
+		return &stepFieldError{
+			"neighbors",
+			fmt.Errorf(
+				"incorrect branch neighbor data length: got %d, want %d",
+				len(neighborsBytes),
+				expectedNeighborBytes,
+			),
+		}
 	}
 	neighbors := make([]Hash, 0, branchProofNeighborCount)
 	for i := 0; i < len(neighborsBytes); i += HashSize {
@@ -293,53 +500,56 @@ func (s *ProofStep) unmarshalForkStep(constructor cbor.ConstructorDecoder) error
 	}
 	prefixLen, err := decodeNonNegativeInt(fields[0])
 	if err != nil {
-		return fmt.Errorf("invalid prefix length: %w", err)
+		return &stepFieldError{"prefixLength", fmt.Errorf("invalid prefix length: %w", err)}
 	}
 	var neighborConstructor cbor.ConstructorDecoder
 	if err := decodeExact(fields[1], &neighborConstructor); err != nil {
-		return fmt.Errorf("invalid neighbor constructor: %w", err)
+		return &stepFieldError{"neighbor", fmt.Errorf("invalid neighbor constructor: %w", err)}
 	}
 	if neighborConstructor.Tag() != 0 {
-		return fmt.Errorf(
-			"unexpected fork neighbor constructor: %d",
-			neighborConstructor.Tag(),
-		)
+		return &stepFieldError{
+			"neighbor",
+			fmt.Errorf("unexpected fork neighbor constructor: %d", neighborConstructor.Tag()),
+		}
 	}
 	var neighborFields []cbor.RawMessage
 	if err := neighborConstructor.DecodeFields(&neighborFields); err != nil {
 		return err
 	}
 	if len(neighborFields) != 3 {
-		return errors.New("fork neighbor missing fields")
+		return &stepFieldError{"neighbor", errors.New("fork neighbor missing fields")}
 	}
 	neighborIdx, err := decodeNonNegativeInt(neighborFields[0])
 	if err != nil {
-		return fmt.Errorf("invalid fork neighbor index: %w", err)
+		return &stepFieldError{"neighbor.nibble", fmt.Errorf("invalid fork neighbor index: %w", err)}
 	}
 	if neighborIdx > 0xf {
-		return fmt.Errorf("fork neighbor index out of range: %d", neighborIdx)
+		return &stepFieldError{
+			"neighbor.nibble",
+			fmt.Errorf("fork neighbor index out of range: %d", neighborIdx),
+		}
 	}
 	prefixBytes, err := decodeBytes(neighborFields[1])
 	if err != nil {
-		return fmt.Errorf("invalid fork neighbor prefix: %w", err)
+		return &stepFieldError{"neighbor.prefix", fmt.Errorf("invalid fork neighbor prefix: %w", err)}
 	}
 	rootBytes, err := decodeBytes(neighborFields[2])
 	if err != nil {
-		return fmt.Errorf("invalid fork neighbor root: %w", err)
+		return &stepFieldError{"neighbor.root", fmt.Errorf("invalid fork neighbor root: %w", err)}
 	}
 	neighborRoot, err := hashFromBytes(rootBytes)
 	if err != nil {
-		return fmt.Errorf("invalid fork neighbor root: %w", err)
+		return &stepFieldError{"neighbor.root", fmt.Errorf("invalid fork neighbor root: %w", err)}
 	}
 	neighborNibble, err := nibbleFromInt(neighborIdx)
 	if err != nil {
-		return fmt.Errorf("invalid fork neighbor index: %w", err)
+		return &stepFieldError{"neighbor.nibble", fmt.Errorf("invalid fork neighbor index: %w", err)}
 	}
 	s.stepType = ProofStepTypeFork
 	s.prefixLength = prefixLen
 	neighborPrefix, err := individualBytesToNibbles(prefixBytes)
 	if err != nil {
-		return fmt.Errorf("invalid fork neighbor prefix: %w", err)
+		return &stepFieldError{"neighbor.prefix", fmt.Errorf("invalid fork neighbor prefix: %w", err)}
 	}
 	s.neighbor = ProofStepNeighbor{
 		prefix: neighborPrefix,
@@ -359,19 +569,19 @@ func (s *ProofStep) unmarshalLeafStep(constructor cbor.ConstructorDecoder) error
 	}
 	prefixLen, err := decodeNonNegativeInt(fields[0])
 	if err != nil {
-		return fmt.Errorf("invalid prefix length: %w", err)
+		return &stepFieldError{"prefixLength", fmt.Errorf("invalid prefix length: %w", err)}
 	}
 	keyBytes, err := decodeBytes(fields[1])
 	if err != nil {
-		return fmt.Errorf("invalid key: %w", err)
+		return &stepFieldError{"neighbor.key", fmt.Errorf("invalid key: %w", err)}
 	}
 	valueBytes, err := decodeBytes(fields[2])
 	if err != nil {
-		return fmt.Errorf("invalid value: %w", err)
+		return &stepFieldError{"neighbor.value", fmt.Errorf("invalid value: %w", err)}
 	}
 	leafValue, err := hashFromBytes(valueBytes)
 	if err != nil {
-		return fmt.Errorf("invalid value: %w", err)
+		return &stepFieldError{"neighbor.value", fmt.Errorf("invalid value: %w", err)}
 	}
 	s.stepType = ProofStepTypeLeaf
 	s.prefixLength = prefixLen
@@ -439,6 +649,21 @@ func hashFromBytes(data []byte) (Hash, error) {
 }
 
 func merkleProof(nodes []Node, myIdx int) []Hash {
+	// Gather child node hashes
+	tmpHashes := make([]Hash, 0, len(nodes))
+	for _, child := range nodes {
+		tmpHash := NullHash
+		if child != nil {
+			tmpHash = child.Hash()
+		}
+		tmpHashes = append(tmpHashes, tmpHash)
+	}
+	return merkleProofHashes(tmpHashes, myIdx)
+}
+
+// merkleProofHashes calculates the merkle proof for the child at the specified index from
+// the hashes of all 16 children
+func merkleProofHashes(tmpHashes []Hash, myIdx int) []Hash {
 	var ret []Hash
 	pivot := 8
 	n := 8
@@ -446,16 +671,16 @@ func merkleProof(nodes []Node, myIdx int) []Hash {
 		if myIdx < pivot {
 			ret = append(
 				ret,
-				merkleRoot(
-					nodes[pivot:pivot+n],
+				merkleRootHashes(
+					tmpHashes[pivot:pivot+n],
 				),
 			)
 			pivot -= (n >> 1)
 		} else {
 			ret = append(
 				ret,
-				merkleRoot(
-					nodes[pivot-n:pivot],
+				merkleRootHashes(
+					tmpHashes[pivot-n:pivot],
 				),
 			)
 			pivot += (n >> 1)
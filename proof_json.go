@@ -0,0 +1,216 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// proofStepJson is the JSON representation of a proof step used by the JS
+// merkle-patricia-forestry library
+type proofStepJson struct {
+	Type      string          `json:"type"`
+	Skip      int             `json:"skip"`
+	Neighbors string          `json:"neighbors,omitempty"`
+	Neighbor  json.RawMessage `json:"neighbor,omitempty"`
+}
+
+type proofStepForkNeighborJson struct {
+	Nibble *int   `json:"nibble"`
+	Prefix string `json:"prefix"`
+	Root   string `json:"root"`
+}
+
+type proofStepLeafNeighborJson struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (p *Proof) MarshalJSON() ([]byte, error) {
+	tmpSteps := make([]*ProofStep, 0, len(p.steps))
+	for i := range p.steps {
+		tmpSteps = append(tmpSteps, &p.steps[i])
+	}
+	return json.Marshal(tmpSteps)
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	*p = Proof{}
+	var tmpSteps []ProofStep
+	if err := json.Unmarshal(data, &tmpSteps); err != nil {
+		return err
+	}
+	p.steps = tmpSteps
+	return nil
+}
+
+func (s *ProofStep) MarshalJSON() ([]byte, error) {
+	switch s.stepType {
+	case ProofStepTypeBranch:
+		tmpNeighbors := make([]byte, 0, len(s.neighbors)*HashSize)
+		for _, neighbor := range s.neighbors {
+			tmpNeighbors = append(tmpNeighbors, neighbor.Bytes()...)
+		}
+		return json.Marshal(
+			proofStepJson{
+				Type:      s.stepType.String(),
+				Skip:      s.prefixLength,
+				Neighbors: hex.EncodeToString(tmpNeighbors),
+			},
+		)
+	case ProofStepTypeFork:
+		nibble := int(s.neighbor.nibble)
+		return s.marshalJsonNeighbor(
+			proofStepForkNeighborJson{
+				Nibble: &nibble,
+				Prefix: hex.EncodeToString(
+					nibblesToIndividualBytes(s.neighbor.prefix),
+				),
+				Root: s.neighbor.root.String(),
+			},
+		)
+	case ProofStepTypeLeaf:
+		return s.marshalJsonNeighbor(
+			proofStepLeafNeighborJson{
+				Key:   hex.EncodeToString(nibblesToBytes(s.neighbor.key)),
+				Value: s.neighbor.value.String(),
+			},
+		)
+	default:
+		return nil, errors.New("unknown proof step type")
+	}
+}
+
+func (s *ProofStep) marshalJsonNeighbor(neighbor any) ([]byte, error) {
+	neighborJson, err := json.Marshal(neighbor)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(
+		proofStepJson{
+			Type:     s.stepType.String(),
+			Skip:     s.prefixLength,
+			Neighbor: neighborJson,
+		},
+	)
+}
+
+func (s *ProofStep) UnmarshalJSON(data []byte) error {
+	*s = ProofStep{}
+	var tmpStep proofStepJson
+	if err := json.Unmarshal(data, &tmpStep); err != nil {
+		return err
+	}
+	if tmpStep.Skip < 0 {
+		return fmt.Errorf("invalid skip: negative value: %d", tmpStep.Skip)
+	}
+	switch tmpStep.Type {
+	case ProofStepTypeBranch.String():
+		neighborsBytes, err := hex.DecodeString(tmpStep.Neighbors)
+		if err != nil {
+			return fmt.Errorf("invalid neighbors: %w", err)
+		}
+		expectedNeighborBytes := branchProofNeighborCount * HashSize
+		if len(neighborsBytes) != expectedNeighborBytes {
+			return fmt.Errorf(
+				"incorrect branch neighbor data length: got %d, want %d",
+				len(neighborsBytes),
+				expectedNeighborBytes,
+			)
+		}
+		neighbors := make([]Hash, 0, branchProofNeighborCount)
+		for i := 0; i < len(neighborsBytes); i += HashSize {
+			neighborHash, err := hashFromBytes(neighborsBytes[i : i+HashSize])
+			if err != nil {
+				return err
+			}
+			neighbors = append(neighbors, neighborHash)
+		}
+		s.stepType = ProofStepTypeBranch
+		s.prefixLength = tmpStep.Skip
+		s.neighbors = neighbors
+	case ProofStepTypeFork.String():
+		var tmpNeighbor proofStepForkNeighborJson
+		if err := unmarshalJsonNeighbor(tmpStep.Neighbor, &tmpNeighbor); err != nil {
+			return fmt.Errorf("invalid fork neighbor: %w", err)
+		}
+		if tmpNeighbor.Nibble == nil {
+			return errors.New("fork neighbor missing nibble")
+		}
+		neighborNibble, err := nibbleFromInt(*tmpNeighbor.Nibble)
+		if err != nil {
+			return fmt.Errorf("invalid fork neighbor index: %w", err)
+		}
+		prefixBytes, err := hex.DecodeString(tmpNeighbor.Prefix)
+		if err != nil {
+			return fmt.Errorf("invalid fork neighbor prefix: %w", err)
+		}
+		neighborPrefix, err := individualBytesToNibbles(prefixBytes)
+		if err != nil {
+			return fmt.Errorf("invalid fork neighbor prefix: %w", err)
+		}
+		neighborRoot, err := hashFromHexString(tmpNeighbor.Root)
+		if err != nil {
+			return fmt.Errorf("invalid fork neighbor root: %w", err)
+		}
+		s.stepType = ProofStepTypeFork
+		s.prefixLength = tmpStep.Skip
+		s.neighbor = ProofStepNeighbor{
+			prefix: neighborPrefix,
+			nibble: neighborNibble,
+			root:   neighborRoot,
+		}
+	case ProofStepTypeLeaf.String():
+		var tmpNeighbor proofStepLeafNeighborJson
+		if err := unmarshalJsonNeighbor(tmpStep.Neighbor, &tmpNeighbor); err != nil {
+			return fmt.Errorf("invalid leaf neighbor: %w", err)
+		}
+		keyBytes, err := hex.DecodeString(tmpNeighbor.Key)
+		if err != nil {
+			return fmt.Errorf("invalid key: %w", err)
+		}
+		leafValue, err := hashFromHexString(tmpNeighbor.Value)
+		if err != nil {
+			return fmt.Errorf("invalid value: %w", err)
+		}
+		s.stepType = ProofStepTypeLeaf
+		s.prefixLength = tmpStep.Skip
+		s.neighbor = ProofStepNeighbor{
+			key:   bytesToNibbles(keyBytes),
+			value: leafValue,
+		}
+	default:
+		return fmt.Errorf("unknown proof step type: %q", tmpStep.Type)
+	}
+	return nil
+}
+
+func unmarshalJsonNeighbor(data json.RawMessage, dest any) error {
+	if len(data) == 0 {
+		return errors.New("missing neighbor")
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func hashFromHexString(data string) (Hash, error) {
+	tmpBytes, err := hex.DecodeString(data)
+	if err != nil {
+		return Hash{}, err
+	}
+	return hashFromBytes(tmpBytes)
+}
@@ -0,0 +1,299 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProofMarshalJson(t *testing.T) {
+	neighborRoot := HashValue([]byte("neighbor"))
+	leafValue := HashValue([]byte("value"))
+	leafKey := keyToPath([]byte("key"))
+	proof := &Proof{
+		steps: []ProofStep{
+			{
+				stepType:     ProofStepTypeBranch,
+				prefixLength: 0,
+				neighbors: []Hash{
+					HashValue([]byte("a")),
+					HashValue([]byte("b")),
+					HashValue([]byte("c")),
+					NullHash,
+				},
+			},
+			{
+				stepType:     ProofStepTypeFork,
+				prefixLength: 2,
+				neighbor: ProofStepNeighbor{
+					prefix: []Nibble{0x0, 0x7, 0xa},
+					nibble: 0x3,
+					root:   neighborRoot,
+				},
+			},
+			{
+				stepType:     ProofStepTypeLeaf,
+				prefixLength: 1,
+				neighbor: ProofStepNeighbor{
+					key:   leafKey,
+					value: leafValue,
+				},
+			},
+		},
+	}
+	proofJson, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof as JSON: %s", err)
+	}
+	expectedJson := `[{"type":"branch","skip":0,"neighbors":"` +
+		HashValue([]byte("a")).String() +
+		HashValue([]byte("b")).String() +
+		HashValue([]byte("c")).String() +
+		NullHash.String() +
+		`"},{"type":"fork","skip":2,"neighbor":{"nibble":3,"prefix":"00070a","root":"` +
+		neighborRoot.String() +
+		`"}},{"type":"leaf","skip":1,"neighbor":{"key":"` +
+		hex.EncodeToString(nibblesToBytes(leafKey)) +
+		`","value":"` +
+		leafValue.String() +
+		`"}}]`
+	if string(proofJson) != expectedJson {
+		t.Fatalf(
+			"did not get expected proof JSON\n  got:    %s\n  wanted: %s",
+			proofJson,
+			expectedJson,
+		)
+	}
+	var decoded Proof
+	if err := json.Unmarshal(proofJson, &decoded); err != nil {
+		t.Fatalf("got unexpected error when decoding proof JSON: %s", err)
+	}
+	assertProofStepsEqual(t, &decoded, proof)
+}
+
+func TestProofJsonRoundTripCbor(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	for _, entry := range fruitsTestEntries {
+		proof, err := trie.Prove([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		proofJson, err := json.Marshal(proof)
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof as JSON: %s", err)
+		}
+		var decoded Proof
+		if err := json.Unmarshal(proofJson, &decoded); err != nil {
+			t.Fatalf("got unexpected error when decoding proof JSON: %s", err)
+		}
+		wantCbor, err := proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof as CBOR: %s", err)
+		}
+		gotCbor, err := decoded.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof as CBOR: %s", err)
+		}
+		if !bytes.Equal(gotCbor, wantCbor) {
+			t.Fatalf("proof CBOR mismatch after JSON round-trip for key %q", entry.key)
+		}
+		if !decoded.Verify(trie.Hash(), []byte(entry.key), []byte(entry.value)) {
+			t.Fatalf("decoded proof did not verify for key %q", entry.key)
+		}
+	}
+}
+
+// TestProofUnmarshalJsonFixture checks proofs in the JSON format of Proof.toJSON() in the JS
+// library for the fruits trie. The first line of the fixture records where the proofs came
+// from. The checked-in fixture holds the CBOR test vectors from the
+// aiken-lang/merkle-patricia-forestry tests, rendered in that layout. Running
+// testdata/fruits_proofs_js.mjs replaces it with the output of the JS library, which covers
+// every fruit and records the library version
+func TestProofUnmarshalJsonFixture(t *testing.T) {
+	fixtureFile, err := os.Open("testdata/fruits_proofs_js.jsonl")
+	if err != nil {
+		t.Fatalf("got unexpected error when opening fixture: %s", err)
+	}
+	defer fixtureFile.Close()
+	values := make(map[string]string)
+	for _, entry := range fruitsTestEntries {
+		values[entry.key] = entry.value
+	}
+	fruitsRoot, err := hashFromHexString(fruitsExpectedHash)
+	if err != nil {
+		t.Fatalf("got unexpected error when decoding root hash: %s", err)
+	}
+	scanner := bufio.NewScanner(fixtureFile)
+	scanner.Buffer(nil, 1024*1024)
+	if !scanner.Scan() {
+		t.Fatalf("fixture is missing header: %v", scanner.Err())
+	}
+	var header struct {
+		Source  string `json:"source"`
+		Library string `json:"library"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("got unexpected error when decoding fixture header: %s", err)
+	}
+	generated := header.Library != "" && header.Version != ""
+	if !generated && header.Source == "" {
+		t.Fatalf("fixture header does not record where the proofs came from: %s", scanner.Bytes())
+	}
+	stepTypes := make(map[ProofStepType]bool)
+	count := 0
+	for scanner.Scan() {
+		var fixture struct {
+			Key   string          `json:"key"`
+			Proof json.RawMessage `json:"proof"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &fixture); err != nil {
+			t.Fatalf("got unexpected error when decoding fixture: %s", err)
+		}
+		var decoded Proof
+		if err := json.Unmarshal(fixture.Proof, &decoded); err != nil {
+			t.Fatalf("got unexpected error when decoding proof JSON: %s", err)
+		}
+		for _, step := range decoded.steps {
+			stepTypes[step.stepType] = true
+		}
+		value, ok := values[fixture.Key]
+		if !ok {
+			t.Fatalf("fixture has unknown key %q", fixture.Key)
+		}
+		if !decoded.Verify(fruitsRoot, []byte(fixture.Key), []byte(value)) {
+			t.Fatalf("fixture proof did not verify for key %q", fixture.Key)
+		}
+		proofJson, err := json.Marshal(&decoded)
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof as JSON: %s", err)
+		}
+		if !bytes.Equal(proofJson, fixture.Proof) {
+			t.Fatalf(
+				"did not get expected proof JSON for key %q\n  got:    %s\n  wanted: %s",
+				fixture.Key,
+				proofJson,
+				fixture.Proof,
+			)
+		}
+		// The fixture matches the CBOR test vector for the same key
+		for _, testDef := range proofTestDefs {
+			if string(testDef.key) != fixture.Key {
+				continue
+			}
+			proofCbor, err := decoded.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("got unexpected error when encoding proof as CBOR: %s", err)
+			}
+			if hex.EncodeToString(proofCbor) != testDef.expectedCborHex {
+				t.Fatalf("fixture proof does not match CBOR test vector for key %q", fixture.Key)
+			}
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("got unexpected error when reading fixture: %s", err)
+	}
+	if count == 0 {
+		t.Fatalf("fixture has no proofs")
+	}
+	// The output of the JS library covers every step type, since the fruits trie has proofs
+	// with fork steps
+	if generated {
+		if count != len(fruitsTestEntries) {
+			t.Fatalf(
+				"did not get expected proof count from %s %s: got %d, expected %d",
+				header.Library,
+				header.Version,
+				count,
+				len(fruitsTestEntries),
+			)
+		}
+		for _, stepType := range []ProofStepType{
+			ProofStepTypeBranch,
+			ProofStepTypeFork,
+			ProofStepTypeLeaf,
+		} {
+			if !stepTypes[stepType] {
+				t.Fatalf("fixture from %s %s has no %s steps", header.Library, header.Version, stepType)
+			}
+		}
+	}
+}
+
+func TestProofUnmarshalJsonErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		json          string
+		expectedError string
+	}{
+		{
+			name:          "unknown type",
+			json:          `[{"type":"foo","skip":0}]`,
+			expectedError: "unknown proof step type",
+		},
+		{
+			name:          "negative skip",
+			json:          `[{"type":"branch","skip":-1,"neighbors":""}]`,
+			expectedError: "negative value",
+		},
+		{
+			name:          "short branch neighbors",
+			json:          `[{"type":"branch","skip":0,"neighbors":"0102"}]`,
+			expectedError: "incorrect branch neighbor data length",
+		},
+		{
+			name:          "missing fork neighbor",
+			json:          `[{"type":"fork","skip":0}]`,
+			expectedError: "missing neighbor",
+		},
+		{
+			name:          "fork nibble out of range",
+			json:          `[{"type":"fork","skip":0,"neighbor":{"nibble":16,"prefix":"","root":""}}]`,
+			expectedError: "nibble out of range",
+		},
+		{
+			name:          "fork prefix out of range",
+			json:          `[{"type":"fork","skip":0,"neighbor":{"nibble":1,"prefix":"10","root":""}}]`,
+			expectedError: "out of nibble range",
+		},
+		{
+			name:          "leaf short value",
+			json:          `[{"type":"leaf","skip":0,"neighbor":{"key":"00","value":"00"}}]`,
+			expectedError: "expected 32 bytes for hash",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var decoded Proof
+			err := json.Unmarshal([]byte(tc.json), &decoded)
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
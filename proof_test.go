@@ -290,6 +290,205 @@ func TestProofUnmarshalRejectsInvalidBranchNeighborLength(t *testing.T) {
 	}
 }
 
+func TestProofMarshalCborDefinite(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	for _, entry := range fruitsTestEntries {
+		proof, err := trie.Prove([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		indefCbor, err := proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		defCbor, err := proof.MarshalCBORWithOptions(EncodeDefinite)
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		if defCbor[0] == 0x9f {
+			t.Fatalf("definite proof CBOR uses indefinite-length list: %x", defCbor)
+		}
+		var decoded Proof
+		if err := decoded.UnmarshalCBOR(defCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding definite proof CBOR: %s", err)
+		}
+		assertProofStepsEqual(t, &decoded, proof)
+		roundTripCbor, err := decoded.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when re-encoding proof: %s", err)
+		}
+		if !bytes.Equal(roundTripCbor, indefCbor) {
+			t.Fatal("indefinite proof CBOR mismatch after definite round-trip")
+		}
+		sizeDiff, err := proof.CBORSizeDifference()
+		if err != nil {
+			t.Fatalf("got unexpected error when comparing proof sizes: %s", err)
+		}
+		if sizeDiff != len(indefCbor)-len(defCbor) {
+			t.Fatalf(
+				"did not get expected size difference: got %d, expected %d",
+				sizeDiff,
+				len(indefCbor)-len(defCbor),
+			)
+		}
+		if sizeDiff <= 0 {
+			t.Fatalf("definite proof CBOR is not smaller: difference %d", sizeDiff)
+		}
+	}
+}
+
+func TestProofStepMarshalCborDefinite(t *testing.T) {
+	step := ProofStep{
+		stepType:     ProofStepTypeLeaf,
+		prefixLength: 3,
+		neighbor: ProofStepNeighbor{
+			key:   []Nibble{0x0, 0x1},
+			value: HashValue([]byte("value")),
+		},
+	}
+	defCbor, err := step.MarshalCBORWithOptions(EncodeDefinite)
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding step: %s", err)
+	}
+	// Constructor 2 tag, followed by a definite-length list of 3 items
+	expectedPrefix := []byte{0xd8, 0x7b, 0x83, 0x03}
+	if !bytes.HasPrefix(defCbor, expectedPrefix) {
+		t.Fatalf("did not get expected definite step CBOR: got %x", defCbor)
+	}
+}
+
+func TestProofAccessorsAndConstructors(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	for _, entry := range fruitsTestEntries {
+		proof, err := trie.Prove([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		// Rebuild the proof from its exported accessors
+		var steps []ProofStep
+		for _, step := range proof.Steps() {
+			var newStep ProofStep
+			switch step.Type() {
+			case ProofStepTypeBranch:
+				newStep, err = NewBranchStep(step.PrefixLength(), step.Neighbors())
+			case ProofStepTypeFork:
+				neighbor := step.Neighbor()
+				newStep, err = NewForkStep(
+					step.PrefixLength(),
+					neighbor.Nibble(),
+					neighbor.Prefix(),
+					neighbor.Root(),
+				)
+			case ProofStepTypeLeaf:
+				neighbor := step.Neighbor()
+				newStep, err = NewLeafStep(
+					step.PrefixLength(),
+					neighbor.Key(),
+					neighbor.Value(),
+				)
+			default:
+				t.Fatalf("unexpected proof step type: %s", step.Type())
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error when building proof step: %s", err)
+			}
+			steps = append(steps, newStep)
+		}
+		rebuilt := NewProof(steps...)
+		assertProofStepsEqual(t, rebuilt, proof)
+		if !rebuilt.Verify(trie.Hash(), []byte(entry.key), []byte(entry.value)) {
+			t.Fatalf("rebuilt proof did not verify for key %q", entry.key)
+		}
+	}
+}
+
+func TestProofAccessorsReturnCopies(t *testing.T) {
+	step, err := NewBranchStep(0, make([]Hash, branchProofNeighborCount))
+	if err != nil {
+		t.Fatalf("got unexpected error when building proof step: %s", err)
+	}
+	proof := NewProof(step)
+	proof.Steps()[0].neighbors[0] = HashValue([]byte("modified"))
+	if proof.steps[0].neighbors[0] != NullHash {
+		t.Fatal("modifying returned steps changed the proof")
+	}
+	neighbors := step.Neighbors()
+	neighbors[1] = HashValue([]byte("modified"))
+	if step.neighbors[1] != NullHash {
+		t.Fatal("modifying returned neighbors changed the proof step")
+	}
+}
+
+func TestProofStepConstructorErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		build         func() (ProofStep, error)
+		expectedError string
+	}{
+		{
+			name: "branch negative prefix",
+			build: func() (ProofStep, error) {
+				return NewBranchStep(-1, make([]Hash, branchProofNeighborCount))
+			},
+			expectedError: "negative prefix length",
+		},
+		{
+			name: "branch neighbor count",
+			build: func() (ProofStep, error) {
+				return NewBranchStep(0, make([]Hash, 3))
+			},
+			expectedError: "incorrect branch neighbor count",
+		},
+		{
+			name: "fork nibble out of range",
+			build: func() (ProofStep, error) {
+				return NewForkStep(0, 0x10, nil, NullHash)
+			},
+			expectedError: "invalid fork neighbor index",
+		},
+		{
+			name: "fork prefix out of range",
+			build: func() (ProofStep, error) {
+				return NewForkStep(0, 0x1, []Nibble{0x1, 0x20}, NullHash)
+			},
+			expectedError: "invalid fork neighbor prefix",
+		},
+		{
+			name: "leaf key length",
+			build: func() (ProofStep, error) {
+				return NewLeafStep(0, []Nibble{0x1, 0x2}, NullHash)
+			},
+			expectedError: "incorrect leaf neighbor key length",
+		},
+		{
+			name: "leaf key out of range",
+			build: func() (ProofStep, error) {
+				key := keyToPath([]byte("key"))
+				key[5] = 0xff
+				return NewLeafStep(0, key, NullHash)
+			},
+			expectedError: "invalid leaf neighbor key",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.build()
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestBigTrieProofMarshalCbor(t *testing.T) {
 	trie := NewTrie()
 
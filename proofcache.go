@@ -0,0 +1,82 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import "sync"
+
+// proofCache tracks the proof steps cached on each branch of a trie. The steps are stored on
+// the branches themselves, so that any change to a branch drops the cached steps for it
+// while leaving those for untouched branches in place
+type proofCache struct {
+	mutex  sync.Mutex
+	hits   uint64
+	misses uint64
+}
+
+// ProofCacheStats holds the number of proof steps served from the proof cache and the
+// number that had to be generated
+type ProofCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// WithProofCache enables caching of the proof steps generated for each branch of the trie.
+// A cached step is reused until the branch it belongs to is changed by Set or Delete, which
+// speeds up repeated proofs for keys in parts of the trie that aren't being written to
+func WithProofCache() TrieOption {
+	return func(t *Trie) {
+		t.proofCache = &proofCache{}
+	}
+}
+
+// ProofCacheStats returns the hit and miss counts for the proof cache. The counts are zero
+// when the proof cache isn't enabled
+func (t *Trie) ProofCacheStats() ProofCacheStats {
+	if t.proofCache == nil {
+		return ProofCacheStats{}
+	}
+	t.proofCache.mutex.Lock()
+	defer t.proofCache.mutex.Unlock()
+	return ProofCacheStats{
+		Hits:   t.proofCache.hits,
+		Misses: t.proofCache.misses,
+	}
+}
+
+// proofStep returns the proof step for a path going through the specified child slot of the
+// branch, using the cached step when available
+func (b *Branch) proofStep(childIdx int, cache *proofCache) ProofStep {
+	if cache == nil {
+		return rewindStep(childIdx, len(b.prefix), b.children[:])
+	}
+	cache.mutex.Lock()
+	if step, ok := b.proofSteps[childIdx]; ok {
+		cache.hits++
+		cache.mutex.Unlock()
+		return step.clone()
+	}
+	cache.misses++
+	cache.mutex.Unlock()
+	// Generate the step without holding the lock so that concurrent proofs don't wait on
+	// each other's hashing
+	step := rewindStep(childIdx, len(b.prefix), b.children[:])
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if b.proofSteps == nil {
+		b.proofSteps = make(map[int]ProofStep)
+	}
+	b.proofSteps[childIdx] = step.clone()
+	return step
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestProofCacheMatchesUncached(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	trie := NewTrie()
+	cachedTrie := NewTrie(WithProofCache())
+	for i := range 1000 {
+		key := fmt.Appendf(nil, "key-%d", rng.Intn(200))
+		if rng.Intn(3) == 0 {
+			errA := trie.Delete(key)
+			errB := cachedTrie.Delete(key)
+			if errA != errB {
+				t.Fatalf("got mismatched errors when deleting key: %v, %v", errA, errB)
+			}
+		} else {
+			value := fmt.Appendf(nil, "value-%d", i)
+			trie.Set(key, value)
+			cachedTrie.Set(key, value)
+		}
+		// Prove a handful of keys after each operation, including repeats that should be
+		// served from the cache
+		for range 5 {
+			key := fmt.Appendf(nil, "key-%d", rng.Intn(200))
+			proof, errA := trie.Prove(key)
+			cachedProof, errB := cachedTrie.Prove(key)
+			if errA != errB {
+				t.Fatalf("got mismatched errors when proving key: %v, %v", errA, errB)
+			}
+			if errA != nil {
+				proof, errA = trie.ProveAbsence(key)
+				cachedProof, errB = cachedTrie.ProveAbsence(key)
+				if errA != nil || errB != nil {
+					t.Fatalf("got unexpected errors when proving absence: %v, %v", errA, errB)
+				}
+			}
+			assertProofStepsEqual(t, cachedProof, proof)
+		}
+	}
+	if stats := cachedTrie.ProofCacheStats(); stats.Hits == 0 || stats.Misses == 0 {
+		t.Fatalf("did not get expected cache activity: %+v", stats)
+	}
+}
+
+func TestProofCacheInvalidation(t *testing.T) {
+	trie := NewTrie(WithProofCache())
+	for i := range 200 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	// Find keys whose paths diverge at the root branch
+	keyA := []byte("key-0")
+	var keyB []byte
+	for i := 1; i < 200; i++ {
+		tmpKey := fmt.Appendf(nil, "key-%d", i)
+		if keyToPath(tmpKey)[0] != keyToPath(keyA)[0] {
+			keyB = tmpKey
+			break
+		}
+	}
+	proof, err := trie.Prove(keyA)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	stepCount := uint64(len(proof.steps))
+	expectedStats := ProofCacheStats{Misses: stepCount}
+	if stats := trie.ProofCacheStats(); stats != expectedStats {
+		t.Fatalf("did not get expected cache stats: got %+v, expected %+v", stats, expectedStats)
+	}
+	if _, err := trie.Prove(keyA); err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	expectedStats.Hits += stepCount
+	if stats := trie.ProofCacheStats(); stats != expectedStats {
+		t.Fatalf("did not get expected cache stats: got %+v, expected %+v", stats, expectedStats)
+	}
+	// Changing a key in another subtree should only invalidate the root branch
+	trie.Set(keyB, []byte("new value"))
+	if _, err := trie.Prove(keyA); err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	expectedStats.Hits += stepCount - 1
+	expectedStats.Misses++
+	if stats := trie.ProofCacheStats(); stats != expectedStats {
+		t.Fatalf("did not get expected cache stats: got %+v, expected %+v", stats, expectedStats)
+	}
+	// Changing the key itself invalidates every branch on its path
+	trie.Set(keyA, []byte("new value"))
+	proof, err = trie.Prove(keyA)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	expectedStats.Misses += stepCount
+	if stats := trie.ProofCacheStats(); stats != expectedStats {
+		t.Fatalf("did not get expected cache stats: got %+v, expected %+v", stats, expectedStats)
+	}
+	if !proof.Verify(trie.Hash(), keyA, []byte("new value")) {
+		t.Fatalf("proof did not verify after update")
+	}
+}
+
+func TestProofCacheDisabled(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	trie.Set([]byte("bcde"), []byte("2"))
+	if _, err := trie.Prove([]byte("abcd")); err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	if stats := trie.ProofCacheStats(); stats != (ProofCacheStats{}) {
+		t.Fatalf("did not get expected empty cache stats: got %+v", stats)
+	}
+}
@@ -0,0 +1,531 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// knownHashes tracks the hashes that a verifier learns while checking a sequence of proofs
+// for the same trie, so that they can be left out of later proofs in the sequence. A block
+// is an aligned range of child slots of the branch at a path, and its hash is the merkle root
+// of those children. Since the trie for a set of keys is unique, the hash of a block only
+// changes when a key under it changes
+type knownHashes struct {
+	blocks map[string]Hash
+	values map[string]Hash
+}
+
+func newKnownHashes() *knownHashes {
+	return &knownHashes{
+		blocks: make(map[string]Hash),
+		values: make(map[string]Hash),
+	}
+}
+
+// blockKey returns the key for the block of the specified size starting at the specified
+// child slot of the branch at the path
+func blockKey(path []Nibble, start int, size int) string {
+	ret := make([]byte, 0, len(path)+2)
+	for _, nibble := range path {
+		ret = append(ret, byte(nibble))
+	}
+	// Nibbles are below 0x10, so the start can't be mistaken for part of the path
+	return string(append(ret, byte(0x10+start), byte(size)))
+}
+
+// neighborBlockKeys returns the keys for the blocks covered by the neighbor hashes of a
+// branch proof step for the specified child slot of the branch at the path
+func neighborBlockKeys(path []Nibble, childIdx int) []string {
+	ret := make([]string, 0, branchProofNeighborCount)
+	for level := range branchProofNeighborCount {
+		size := 8 >> level
+		ret = append(ret, blockKey(path, ((childIdx/size)^1)*size, size))
+	}
+	return ret
+}
+
+// stepPosition returns the path of the branch described by the proof step starting at the
+// specified position in the path, along with the child slot on the path
+func stepPosition(path []Nibble, cursor int, prefixLength int) ([]Nibble, int, error) {
+	nextCursor := cursor + 1 + prefixLength
+	if prefixLength < 0 || nextCursor > len(path) {
+		return nil, 0, newMalformedProofError("prefixLength", "prefix length exceeds remaining path")
+	}
+	return path[:nextCursor-1], int(path[nextCursor-1]), nil
+}
+
+// forkNeighborPath returns the path at which the children of a fork neighbor start
+func forkNeighborPath(branchPath []Nibble, nibble Nibble, prefix []Nibble) []Nibble {
+	ret := make([]Nibble, 0, len(branchPath)+1+len(prefix))
+	ret = append(ret, branchPath...)
+	ret = append(ret, nibble)
+	return append(ret, prefix...)
+}
+
+// stepNeighbors returns the merkle proof hashes for the child slot on the path of the branch
+// described by the proof step
+func stepNeighbors(step *ProofStep, branchPath []Nibble, childIdx int) ([]Hash, error) {
+	switch step.stepType {
+	case ProofStepTypeBranch:
+		if len(step.neighbors) != branchProofNeighborCount {
+			return nil, newMalformedProofError(
+				"neighbors",
+				"incorrect branch neighbor count: got %d, want %d",
+				len(step.neighbors),
+				branchProofNeighborCount,
+			)
+		}
+		return step.neighbors, nil
+	case ProofStepTypeFork:
+		var tmpHashes [16]Hash
+		tmpHashes[step.neighbor.nibble] = branchHash(step.neighbor.prefix, step.neighbor.root)
+		return merkleProofHashes(tmpHashes[:], childIdx), nil
+	case ProofStepTypeLeaf:
+		nextCursor := len(branchPath) + 1
+		if len(step.neighbor.key) != HashSize*2 {
+			return nil, newMalformedProofError("neighbor.key", "incorrect leaf neighbor key length")
+		}
+		var tmpHashes [16]Hash
+		tmpHashes[step.neighbor.key[nextCursor-1]] = leafHash(
+			step.neighbor.key[nextCursor:],
+			step.neighbor.value,
+		)
+		return merkleProofHashes(tmpHashes[:], childIdx), nil
+	default:
+		return nil, newMalformedProofError("type", "unknown proof step type: %d", step.stepType)
+	}
+}
+
+// learn records the hashes shown by a proof for the specified path, after an operation which
+// leaves the key present with the specified value hash, or removes it when present is false.
+// The neighbors in the proof are not changed by the operation, while anything covering the
+// path is replaced by the hashes computed from the proof
+func (k *knownHashes) learn(
+	path []Nibble,
+	proof *Proof,
+	present bool,
+	valueHash Hash,
+) error {
+	cursors, err := proof.stepCursors(path)
+	if err != nil {
+		return err
+	}
+	for i := range proof.steps {
+		step := &proof.steps[i]
+		branchPath, childIdx, err := stepPosition(path, cursors[i], step.prefixLength)
+		if err != nil {
+			return stepError(i, err)
+		}
+		neighbors, err := stepNeighbors(step, branchPath, childIdx)
+		if err != nil {
+			return stepError(i, err)
+		}
+		for level, tmpKey := range neighborBlockKeys(branchPath, childIdx) {
+			k.blocks[tmpKey] = neighbors[level]
+		}
+		switch step.stepType {
+		case ProofStepTypeFork:
+			neighborPath := forkNeighborPath(branchPath, step.neighbor.nibble, step.neighbor.prefix)
+			k.blocks[blockKey(neighborPath, 0, 16)] = step.neighbor.root
+		case ProofStepTypeLeaf:
+			k.values[string(nibblesToIndividualBytes(step.neighbor.key))] = step.neighbor.value
+		}
+	}
+	for i := range path {
+		for size := 1; size <= 16; size *= 2 {
+			delete(k.blocks, blockKey(path[:i], int(path[i])/size*size, size))
+		}
+	}
+	delete(k.values, string(nibblesToIndividualBytes(path)))
+	lastIdx := len(proof.steps) - 1
+	var childHash Hash
+	if present {
+		k.values[string(nibblesToIndividualBytes(path))] = valueHash
+		childHash = leafHash(path[cursors[len(proof.steps)]:], valueHash)
+	} else {
+		if lastIdx < 0 {
+			return nil
+		}
+		// A branch with one other child collapses into that child, which takes its place
+		// in the branch above
+		if lastStep := &proof.steps[lastIdx]; lastStep.stepType != ProofStepTypeBranch {
+			childHash, err = lastStep.excludedHash(path, cursors[lastIdx])
+			if err != nil {
+				return stepError(lastIdx, err)
+			}
+			lastIdx--
+		}
+	}
+	for i := lastIdx; i >= 0; i-- {
+		step := &proof.steps[i]
+		branchPath, childIdx, err := stepPosition(path, cursors[i], step.prefixLength)
+		if err != nil {
+			return stepError(i, err)
+		}
+		neighbors, err := stepNeighbors(step, branchPath, childIdx)
+		if err != nil {
+			return stepError(i, err)
+		}
+		tmpHash := childHash
+		k.blocks[blockKey(branchPath, childIdx, 1)] = tmpHash
+		for level := branchProofNeighborCount - 1; level >= 0; level-- {
+			size := 8 >> level
+			if childIdx&size != 0 {
+				tmpHash = HashValue(append(neighbors[level].Bytes(), tmpHash.Bytes()...))
+			} else {
+				tmpHash = HashValue(append(tmpHash.Bytes(), neighbors[level].Bytes()...))
+			}
+			k.blocks[blockKey(branchPath, childIdx/(size*2)*(size*2), size*2)] = tmpHash
+		}
+		childHash = branchHash(branchPath[cursors[i]:], tmpHash)
+	}
+	return nil
+}
+
+// checkKnown returns whether the hash for the key is known, checking that it matches the
+// specified hash
+func checkKnown(hashes map[string]Hash, key string, expected Hash) (bool, error) {
+	tmpHash, ok := hashes[key]
+	if !ok {
+		return false, nil
+	}
+	if tmpHash != expected {
+		return false, fmt.Errorf("known hash does not match proof: %s", tmpHash)
+	}
+	return true, nil
+}
+
+// compressStep returns the encoding of the proof step starting at the specified position in
+// the path, with the hashes that are already known left out. A step with no known hashes
+// uses the usual proof step encoding, while a step with known hashes has the same layout
+// with those hashes removed. For a branch step, the neighbors field holds only the unknown
+// neighbor hashes, in order, and for a fork or leaf step, a known hash is an empty byte string
+func (k *knownHashes) compressStep(path []Nibble, cursor int, step *ProofStep) ([]byte, error) {
+	branchPath, childIdx, err := stepPosition(path, cursor, step.prefixLength)
+	if err != nil {
+		return nil, err
+	}
+	switch step.stepType {
+	case ProofStepTypeBranch:
+		if len(step.neighbors) != branchProofNeighborCount {
+			return nil, newMalformedProofError("neighbors", "incorrect branch neighbor count")
+		}
+		var tmpNeighbors []byte
+		for level, tmpKey := range neighborBlockKeys(branchPath, childIdx) {
+			ok, err := checkKnown(k.blocks, tmpKey, step.neighbors[level])
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				tmpNeighbors = append(tmpNeighbors, step.neighbors[level].Bytes()...)
+			}
+		}
+		if len(tmpNeighbors) == branchProofNeighborCount*HashSize {
+			return step.MarshalCBOR()
+		}
+		tmpData := cbor.NewConstructorEncoder(
+			0,
+			cbor.IndefLengthList{step.prefixLength, tmpNeighbors},
+		)
+		return cbor.Encode(tmpData)
+	case ProofStepTypeFork:
+		neighborPath := forkNeighborPath(branchPath, step.neighbor.nibble, step.neighbor.prefix)
+		ok, err := checkKnown(k.blocks, blockKey(neighborPath, 0, 16), step.neighbor.root)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return step.MarshalCBOR()
+		}
+		tmpData := cbor.NewConstructorEncoder(
+			1,
+			cbor.IndefLengthList{
+				step.prefixLength,
+				cbor.NewConstructorEncoder(
+					0,
+					cbor.IndefLengthList{
+						int(step.neighbor.nibble),
+						nibblesToIndividualBytes(step.neighbor.prefix),
+						[]byte{},
+					},
+				),
+			},
+		)
+		return cbor.Encode(tmpData)
+	case ProofStepTypeLeaf:
+		ok, err := checkKnown(
+			k.values,
+			string(nibblesToIndividualBytes(step.neighbor.key)),
+			step.neighbor.value,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return step.MarshalCBOR()
+		}
+		tmpData := cbor.NewConstructorEncoder(
+			2,
+			cbor.IndefLengthList{
+				step.prefixLength,
+				nibblesToBytes(step.neighbor.key),
+				[]byte{},
+			},
+		)
+		return cbor.Encode(tmpData)
+	default:
+		return nil, errors.New("unknown proof step type")
+	}
+}
+
+// expandStep decodes a proof step encoded by compressStep, filling in the known hashes
+func (k *knownHashes) expandStep(path []Nibble, cursor int, data []byte) (ProofStep, error) {
+	var constructor cbor.ConstructorDecoder
+	if err := decodeExact(data, &constructor); err != nil {
+		return ProofStep{}, err
+	}
+	var fields []cbor.RawMessage
+	if err := constructor.DecodeFields(&fields); err != nil {
+		return ProofStep{}, err
+	}
+	if len(fields) < 2 {
+		return ProofStep{}, errors.New("missing fields")
+	}
+	prefixLength, err := decodeNonNegativeInt(fields[0])
+	if err != nil {
+		return ProofStep{}, &stepFieldError{"prefixLength", fmt.Errorf("invalid prefix length: %w", err)}
+	}
+	branchPath, childIdx, err := stepPosition(path, cursor, prefixLength)
+	if err != nil {
+		return ProofStep{}, err
+	}
+	switch constructor.Tag() {
+	case 0:
+		if len(fields) != 2 {
+			return ProofStep{}, errors.New("missing fields")
+		}
+		tmpNeighbors, err := decodeBytes(fields[1])
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbors", fmt.Errorf("invalid neighbors: %w", err)}
+		}
+		neighbors := make([]Hash, 0, branchProofNeighborCount)
+		for _, tmpKey := range neighborBlockKeys(branchPath, childIdx) {
+			if tmpHash, ok := k.blocks[tmpKey]; ok {
+				neighbors = append(neighbors, tmpHash)
+				continue
+			}
+			if len(tmpNeighbors) < HashSize {
+				return ProofStep{}, &stepFieldError{"neighbors", errors.New("missing neighbor hash")}
+			}
+			neighbors = append(neighbors, Hash(tmpNeighbors[:HashSize]))
+			tmpNeighbors = tmpNeighbors[HashSize:]
+		}
+		if len(tmpNeighbors) > 0 {
+			return ProofStep{}, &stepFieldError{"neighbors", errors.New("unexpected neighbor hash")}
+		}
+		return NewBranchStep(prefixLength, neighbors)
+	case 1:
+		if len(fields) != 2 {
+			return ProofStep{}, errors.New("missing fields")
+		}
+		var neighborConstructor cbor.ConstructorDecoder
+		if err := decodeExact(fields[1], &neighborConstructor); err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor", fmt.Errorf("invalid neighbor constructor: %w", err)}
+		}
+		var neighborFields []cbor.RawMessage
+		if err := neighborConstructor.DecodeFields(&neighborFields); err != nil {
+			return ProofStep{}, err
+		}
+		if neighborConstructor.Tag() != 0 || len(neighborFields) != 3 {
+			return ProofStep{}, &stepFieldError{"neighbor", errors.New("invalid fork neighbor")}
+		}
+		tmpNibble, err := decodeNonNegativeInt(neighborFields[0])
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.nibble", fmt.Errorf("invalid fork neighbor index: %w", err)}
+		}
+		nibble, err := nibbleFromInt(tmpNibble)
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.nibble", fmt.Errorf("invalid fork neighbor index: %w", err)}
+		}
+		prefixBytes, err := decodeBytes(neighborFields[1])
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.prefix", fmt.Errorf("invalid fork neighbor prefix: %w", err)}
+		}
+		prefix, err := individualBytesToNibbles(prefixBytes)
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.prefix", fmt.Errorf("invalid fork neighbor prefix: %w", err)}
+		}
+		rootBytes, err := decodeBytes(neighborFields[2])
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.root", fmt.Errorf("invalid fork neighbor root: %w", err)}
+		}
+		neighborPath := forkNeighborPath(branchPath, nibble, prefix)
+		root, ok := k.blocks[blockKey(neighborPath, 0, 16)]
+		if !ok {
+			root, err = hashFromBytes(rootBytes)
+		} else if len(rootBytes) != 0 {
+			err = errors.New("unexpected fork neighbor root")
+		}
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.root", err}
+		}
+		return NewForkStep(prefixLength, nibble, prefix, root)
+	case 2:
+		if len(fields) != 3 {
+			return ProofStep{}, errors.New("missing fields")
+		}
+		keyBytes, err := decodeBytes(fields[1])
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.key", fmt.Errorf("invalid key: %w", err)}
+		}
+		valueBytes, err := decodeBytes(fields[2])
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.value", fmt.Errorf("invalid value: %w", err)}
+		}
+		key := bytesToNibbles(keyBytes)
+		value, ok := k.values[string(nibblesToIndividualBytes(key))]
+		if !ok {
+			value, err = hashFromBytes(valueBytes)
+		} else if len(valueBytes) != 0 {
+			err = errors.New("unexpected leaf neighbor value")
+		}
+		if err != nil {
+			return ProofStep{}, &stepFieldError{"neighbor.value", err}
+		}
+		return NewLeafStep(prefixLength, key, value)
+	default:
+		return ProofStep{}, fmt.Errorf("unknown proof step constructor: %d", constructor.Tag())
+	}
+}
+
+// proofTable holds a sequence of proofs for keys in the same trie, where each proof may
+// leave out hashes known from the proofs before it. Each distinct encoded step is stored
+// once and the proofs refer to the steps by index
+type proofTable struct {
+	steps  []cbor.RawMessage
+	proofs [][]int
+	lookup map[string]int
+}
+
+func newProofTable() *proofTable {
+	return &proofTable{
+		lookup: make(map[string]int),
+	}
+}
+
+// add appends the proof for the specified path, leaving out the known hashes and reusing any
+// identical steps that are already present
+func (p *proofTable) add(known *knownHashes, path []Nibble, proof *Proof) error {
+	cursors, err := proof.stepCursors(path)
+	if err != nil {
+		return err
+	}
+	stepIdxs := make([]int, 0, len(proof.steps))
+	for i := range proof.steps {
+		stepCbor, err := known.compressStep(path, cursors[i], &proof.steps[i])
+		if err != nil {
+			return stepError(i, err)
+		}
+		stepIdx, ok := p.lookup[string(stepCbor)]
+		if !ok {
+			stepIdx = len(p.steps)
+			p.steps = append(p.steps, stepCbor)
+			p.lookup[string(stepCbor)] = stepIdx
+		}
+		stepIdxs = append(stepIdxs, stepIdx)
+	}
+	p.proofs = append(p.proofs, stepIdxs)
+	return nil
+}
+
+// proof returns the proof at the specified index for the path, filling in the known hashes
+func (p *proofTable) proof(known *knownHashes, idx int, path []Nibble) (*Proof, error) {
+	ret := newProof(nil, nil)
+	cursor := 0
+	for i, stepIdx := range p.proofs[idx] {
+		step, err := known.expandStep(path, cursor, p.steps[stepIdx])
+		if err != nil {
+			return nil, stepError(i, err)
+		}
+		ret.steps = append(ret.steps, step)
+		cursor += 1 + step.prefixLength
+	}
+	return ret, nil
+}
+
+// encode returns the step and proof lists for the CBOR encoding
+func (p *proofTable) encode() (any, any) {
+	tmpSteps := make([]any, 0, len(p.steps))
+	for _, step := range p.steps {
+		tmpSteps = append(tmpSteps, step)
+	}
+	tmpProofs := make([]any, 0, len(p.proofs))
+	for _, stepIdxs := range p.proofs {
+		tmpIdxs := make([]any, 0, len(stepIdxs))
+		for _, stepIdx := range stepIdxs {
+			tmpIdxs = append(tmpIdxs, stepIdx)
+		}
+		tmpProofs = append(tmpProofs, cbor.IndefLengthList(tmpIdxs))
+	}
+	return cbor.IndefLengthList(tmpSteps), cbor.IndefLengthList(tmpProofs)
+}
+
+// decode sets the steps and proofs from the lists in the CBOR encoding. The steps can only
+// be fully checked when the proofs are expanded, since the hashes they leave out depend on
+// the proofs before them
+func (p *proofTable) decode(stepsData []byte, proofsData []byte) error {
+	*p = *newProofTable()
+	var tmpSteps []cbor.RawMessage
+	if err := decodeExact(stepsData, &tmpSteps); err != nil {
+		return fmt.Errorf("invalid steps: %w", err)
+	}
+	for stepIdx, step := range tmpSteps {
+		var constructor cbor.ConstructorDecoder
+		if err := decodeExact(step, &constructor); err != nil {
+			return fmt.Errorf("step %d: %w", stepIdx, err)
+		}
+		if _, ok := p.lookup[string(step)]; ok {
+			return fmt.Errorf("step %d: duplicate step", stepIdx)
+		}
+		p.lookup[string(step)] = stepIdx
+	}
+	p.steps = tmpSteps
+	var tmpProofs [][]uint64
+	if err := decodeExact(proofsData, &tmpProofs); err != nil {
+		return fmt.Errorf("invalid step indexes: %w", err)
+	}
+	for proofIdx, tmpIdxs := range tmpProofs {
+		if len(tmpIdxs) > maxProofSteps {
+			return fmt.Errorf("proof %d: too many steps: %d", proofIdx, len(tmpIdxs))
+		}
+		stepIdxs := make([]int, 0, len(tmpIdxs))
+		for _, stepIdx := range tmpIdxs {
+			if stepIdx >= uint64(len(p.steps)) {
+				return fmt.Errorf(
+					"proof %d: step index out of range: %d",
+					proofIdx,
+					stepIdx,
+				)
+			}
+			stepIdxs = append(stepIdxs, int(stepIdx))
+		}
+		p.proofs = append(p.proofs, stepIdxs)
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var errWitnessMismatch = errors.New("witness does not match proof")
+
+// Refresh returns a copy of the proof for the specified key which is updated for a change
+// made to another key in the trie, without access to the trie itself. The witness is the
+// proof for the changed key, as returned in a Transition: a proof in the new trie for an
+// insert, in the old trie for a delete, and in either trie for an update.
+//
+// The refreshed proof should be verified against the new root. ErrInsufficientWitness is
+// returned when a delete collapses a branch in a way that can't be reconstructed from the
+// proof and witness
+func (p *Proof) Refresh(key []byte, op Operation, witness *Proof) (*Proof, error) {
+	if witness == nil {
+		return nil, errors.New("missing witness")
+	}
+	switch op.Type {
+	case OperationInsert, OperationUpdate, OperationDelete:
+	default:
+		return nil, fmt.Errorf("unknown operation type: %d", op.Type)
+	}
+	path := keyToPath(key)
+	opPath := keyToPath(op.Key)
+	if slices.Equal(path, opPath) {
+		// The proof steps only describe neighbors, so they don't change along with the value
+		switch op.Type {
+		case OperationInsert:
+			return nil, ErrKeyExists
+		case OperationDelete:
+			return nil, ErrKeyNotExist
+		}
+		return NewProof(p.steps...), nil
+	}
+	cursors, err := p.stepCursors(path)
+	if err != nil {
+		return nil, err
+	}
+	witnessCursors, err := witness.stepCursors(opPath)
+	if err != nil {
+		return nil, fmt.Errorf("witness: %w", err)
+	}
+	// Find the branch where the changed key leaves the path
+	depth := len(commonPrefix(path, opPath))
+	stepIdx := 0
+	for stepIdx < len(p.steps) && cursors[stepIdx+1] <= depth {
+		stepIdx++
+	}
+	// The steps above that branch are shared by both proofs
+	if len(witness.steps) <= stepIdx {
+		return nil, errWitnessMismatch
+	}
+	for i := range stepIdx {
+		if !p.steps[i].equal(&witness.steps[i]) {
+			return nil, errWitnessMismatch
+		}
+	}
+	if stepIdx == len(p.steps) || depth < cursors[stepIdx+1]-1 {
+		// The changed key diverges within the branch prefix or the leaf suffix, which
+		// can only happen for a new key that splits it
+		if op.Type != OperationInsert {
+			return nil, ErrKeyNotExist
+		}
+		if witness.steps[stepIdx].prefixLength != depth-cursors[stepIdx] {
+			return nil, errWitnessMismatch
+		}
+		ret := NewProof(p.steps...)
+		if stepIdx < len(ret.steps) {
+			ret.steps[stepIdx].prefixLength -= depth - cursors[stepIdx] + 1
+		}
+		step := ProofStep{
+			stepType:     ProofStepTypeLeaf,
+			prefixLength: depth - cursors[stepIdx],
+			neighbor: ProofStepNeighbor{
+				key:   opPath,
+				value: HashValue(op.Value),
+			},
+		}
+		ret.steps = slices.Insert(ret.steps, stepIdx, step)
+		return ret, nil
+	}
+	// The changed key is in another child of the branch
+	if witness.steps[stepIdx].prefixLength != p.steps[stepIdx].prefixLength {
+		return nil, errWitnessMismatch
+	}
+	child, err := witness.mutatedChild(
+		opPath,
+		witnessCursors,
+		stepIdx+1,
+		op.Type != OperationDelete,
+		HashValue(op.Value),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("witness: %w", err)
+	}
+	return p.refreshChild(path, opPath, depth, stepIdx, op, &witness.steps[stepIdx], child)
+}
+
+// refreshChild returns a copy of the proof with the step at stepIdx updated for the new
+// state of the child of that branch which holds the changed key
+func (p *Proof) refreshChild(
+	path []Nibble,
+	opPath []Nibble,
+	depth int,
+	stepIdx int,
+	op Operation,
+	witnessStep *ProofStep,
+	child proofChild,
+) (*Proof, error) {
+	ret := NewProof(p.steps...)
+	step := &ret.steps[stepIdx]
+	childHash := child.hash(depth + 1)
+	switch step.stepType {
+	case ProofStepTypeBranch:
+		if witnessStep.stepType != ProofStepTypeBranch {
+			return nil, errWitnessMismatch
+		}
+		if len(step.neighbors) != branchProofNeighborCount ||
+			len(witnessStep.neighbors) != branchProofNeighborCount {
+			return nil, errors.New("incorrect branch neighbor count")
+		}
+		// Find the smallest group of children containing both keys. The witness
+		// neighbors below that level describe the other children in the group with the
+		// changed key
+		level := 0
+		for (path[depth]^opPath[depth])&(8>>level) == 0 {
+			level++
+		}
+		if op.Type == OperationDelete {
+			// Make sure that the branch keeps at least two other children, otherwise it
+			// collapses into a single neighbor which we only know by its hash
+			otherCount := 0
+			for tmpLevel := range branchProofNeighborCount {
+				if tmpLevel != level && step.neighbors[tmpLevel] != emptyGroupHash(tmpLevel) {
+					otherCount++
+				}
+			}
+			for tmpLevel := level + 1; tmpLevel < branchProofNeighborCount; tmpLevel++ {
+				if witnessStep.neighbors[tmpLevel] != emptyGroupHash(tmpLevel) {
+					otherCount++
+				}
+			}
+			if !child.isNull {
+				otherCount++
+			}
+			if otherCount < 2 {
+				return nil, ErrInsufficientWitness
+			}
+		}
+		step.neighbors[level] = merkleGroupRoot(
+			int(opPath[depth]),
+			childHash,
+			witnessStep.neighbors,
+			level+1,
+		)
+	case ProofStepTypeFork, ProofStepTypeLeaf:
+		var neighborIdx Nibble
+		var neighborHash Hash
+		if step.stepType == ProofStepTypeFork {
+			neighborIdx = step.neighbor.nibble
+			neighborHash = branchHash(step.neighbor.prefix, step.neighbor.root)
+		} else {
+			if len(step.neighbor.key) <= depth {
+				return nil, errors.New("leaf neighbor key is too short")
+			}
+			neighborIdx = step.neighbor.key[depth]
+			neighborHash = leafHash(step.neighbor.key[depth+1:], step.neighbor.value)
+		}
+		if neighborIdx != opPath[depth] {
+			// The changed key goes into an empty slot, which gives the branch a third child
+			if op.Type != OperationInsert {
+				return nil, ErrKeyNotExist
+			}
+			var tmpHashes [16]Hash
+			tmpHashes[neighborIdx] = neighborHash
+			tmpHashes[opPath[depth]] = childHash
+			*step = ProofStep{
+				stepType:     ProofStepTypeBranch,
+				prefixLength: step.prefixLength,
+				neighbors:    merkleProofHashes(tmpHashes[:], int(path[depth])),
+			}
+			break
+		}
+		if child.isNull {
+			// The only neighbor was removed, so the branch collapses and the next node
+			// absorbs its prefix
+			if stepIdx+1 < len(ret.steps) {
+				ret.steps[stepIdx+1].prefixLength += step.prefixLength + 1
+			}
+			ret.steps = slices.Delete(ret.steps, stepIdx, stepIdx+1)
+			break
+		}
+		*step = child.proofStep(step.prefixLength, neighborIdx)
+	default:
+		return nil, errors.New("unknown proof step type")
+	}
+	return ret, nil
+}
+
+// proofChild describes the node in a branch child slot as a leaf or a branch
+type proofChild struct {
+	isLeaf bool
+	isNull bool
+	key    []Nibble
+	value  Hash
+	prefix []Nibble
+	root   Hash
+}
+
+// hash returns the hash of the child node, which starts at the specified path position
+func (c proofChild) hash(cursor int) Hash {
+	switch {
+	case c.isNull:
+		return NullHash
+	case c.isLeaf:
+		return leafHash(c.key[cursor:], c.value)
+	default:
+		return branchHash(c.prefix, c.root)
+	}
+}
+
+// proofStep returns the proof step for a branch whose only neighbor is this child
+func (c proofChild) proofStep(prefixLength int, nibble Nibble) ProofStep {
+	if c.isLeaf {
+		return ProofStep{
+			stepType:     ProofStepTypeLeaf,
+			prefixLength: prefixLength,
+			neighbor: ProofStepNeighbor{
+				key:   c.key,
+				value: c.value,
+			},
+		}
+	}
+	return ProofStep{
+		stepType:     ProofStepTypeFork,
+		prefixLength: prefixLength,
+		neighbor: ProofStepNeighbor{
+			prefix: c.prefix,
+			nibble: nibble,
+			root:   c.root,
+		},
+	}
+}
+
+// mutatedChild returns the node at the position where the proof step at fromIdx starts,
+// with the target leaf included with the specified value hash or, when include is false,
+// removed
+func (p *Proof) mutatedChild(
+	path []Nibble,
+	cursors []int,
+	fromIdx int,
+	include bool,
+	valueHash Hash,
+) (proofChild, error) {
+	if fromIdx == len(p.steps) {
+		if !include {
+			return proofChild{isNull: true}, nil
+		}
+		return proofChild{isLeaf: true, key: slices.Clone(path), value: valueHash}, nil
+	}
+	step := &p.steps[fromIdx]
+	cursor := cursors[fromIdx]
+	nextCursor := cursors[fromIdx+1]
+	if !include && fromIdx == len(p.steps)-1 {
+		// The branch loses the target leaf
+		switch step.stepType {
+		case ProofStepTypeFork:
+			return proofChild{
+				prefix: slices.Concat(
+					path[cursor:cursor+step.prefixLength],
+					[]Nibble{step.neighbor.nibble},
+					step.neighbor.prefix,
+				),
+				root: step.neighbor.root,
+			}, nil
+		case ProofStepTypeLeaf:
+			return proofChild{
+				isLeaf: true,
+				key:    slices.Clone(step.neighbor.key),
+				value:  step.neighbor.value,
+			}, nil
+		}
+	}
+	childHash, err := p.subtreeHash(path, cursors, fromIdx+1, include, valueHash)
+	if err != nil {
+		return proofChild{}, err
+	}
+	root, err := step.childrenRoot(path, cursor, childHash)
+	if err != nil {
+		return proofChild{}, stepError(fromIdx, err)
+	}
+	return proofChild{
+		prefix: slices.Clone(path[cursor : nextCursor-1]),
+		root:   root,
+	}, nil
+}
+
+// emptyGroupHash returns the merkle root of a group of empty children matching the size of
+// the branch proof neighbor at the specified level
+func emptyGroupHash(level int) Hash {
+	return merkleRootHashes(make([]Hash, 8>>level))
+}
+
+// equal returns whether the proof steps are identical
+func (s *ProofStep) equal(other *ProofStep) bool {
+	return s.stepType == other.stepType &&
+		s.prefixLength == other.prefixLength &&
+		slices.Equal(s.neighbors, other.neighbors) &&
+		slices.Equal(s.neighbor.key, other.neighbor.key) &&
+		s.neighbor.value == other.neighbor.value &&
+		slices.Equal(s.neighbor.prefix, other.neighbor.prefix) &&
+		s.neighbor.nibble == other.neighbor.nibble &&
+		s.neighbor.root == other.neighbor.root
+}
@@ -0,0 +1,326 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// splitLevel returns the level of the merkle proof neighbor for a branch which separates the
+// child slots, where level 0 splits the children into halves
+func splitLevel(idxA int, idxB int) int {
+	level := 0
+	for (idxA^idxB)&(8>>level) == 0 {
+		level++
+	}
+	return level
+}
+
+// refreshGroup returns which group of children of a branch known to Refresh holds the child
+// slot, where the proof goes through clientIdx and the witness through opIdx. The proof
+// knows the hash of each group next to its own path, and the witness splits the group
+// holding the changed key in the same way
+func refreshGroup(clientIdx int, opIdx int, idx int) int {
+	level := splitLevel(clientIdx, opIdx)
+	if tmpLevel := splitLevel(clientIdx, idx); tmpLevel != level {
+		return tmpLevel
+	}
+	if idx == opIdx {
+		return -1
+	}
+	return branchProofNeighborCount + splitLevel(opIdx, idx)
+}
+
+// expectInsufficientWitness returns whether refreshing the proof for the key should fail with
+// ErrInsufficientWitness after the operation, given the keys in the trie after it. This is
+// the case for a delete from a branch with a branch step in the proof, when the other
+// children left in the branch are all in one group which the proof and witness only know by
+// its hash. The branch step must then become a fork or leaf step for a single remaining
+// child, which can't be told apart from a group of several children
+func expectInsufficientWitness(key string, op Operation, keys []string) bool {
+	if op.Type != OperationDelete {
+		return false
+	}
+	path := keyToPath([]byte(key))
+	opPath := keyToPath(op.Key)
+	depth := len(commonPrefix(path, opPath))
+	clientIdx := int(path[depth])
+	opIdx := int(opPath[depth])
+	// The other child slots in the branch after the delete
+	slots := make(map[int]bool)
+	for _, tmpKey := range keys {
+		tmpPath := keyToPath([]byte(tmpKey))
+		if len(commonPrefix(path, tmpPath)) == depth {
+			slots[int(tmpPath[depth])] = true
+		}
+	}
+	// Before the delete, the proof has a branch step if there are two other children
+	otherCount := len(slots)
+	if !slots[opIdx] {
+		otherCount++
+	}
+	if otherCount < 2 {
+		return false
+	}
+	groups := make(map[int]bool)
+	for idx := range slots {
+		groups[refreshGroup(clientIdx, opIdx, idx)] = true
+	}
+	return len(groups) < 2
+}
+
+// refreshAllProofs refreshes the proofs for all of the specified keys for a change and
+// checks them against the proofs generated from the updated trie, which holds the specified
+// keys. It returns the number of proofs which could not be refreshed, as expected, and were
+// regenerated instead
+func refreshAllProofs(
+	t *testing.T,
+	trie *Trie,
+	proofs map[string]*Proof,
+	op Operation,
+	witness *Proof,
+	keys []string,
+) int {
+	t.Helper()
+	var insufficientCount int
+	for key, proof := range proofs {
+		if key == string(op.Key) {
+			continue
+		}
+		refreshed, err := proof.Refresh([]byte(key), op, witness)
+		if expectInsufficientWitness(key, op, keys) {
+			if !errors.Is(err, ErrInsufficientWitness) {
+				t.Fatalf(
+					"did not get expected error when refreshing proof for %s on %s: got %v, expected %v",
+					key,
+					op.Type,
+					err,
+					ErrInsufficientWitness,
+				)
+			}
+			insufficientCount++
+			proofs[key], err = trie.Prove([]byte(key))
+			if err != nil {
+				t.Fatalf("got unexpected error when generating proof: %s", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("got unexpected error when refreshing proof for %s on %s: %s", key, op.Type, err)
+		}
+		wantProof, err := trie.Prove([]byte(key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		assertProofStepsEqual(t, refreshed, wantProof)
+		proofs[key] = refreshed
+	}
+	return insufficientCount
+}
+
+func TestProofRefreshRandomMutations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	trie := NewTrie()
+	proofs := make(map[string]*Proof)
+	var keys []string
+	for i := range 50 {
+		key := fmt.Sprintf("key-%d", i)
+		trie.Set([]byte(key), []byte("initial"))
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		proof, err := trie.Prove([]byte(key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		proofs[key] = proof
+	}
+	nextKey := len(keys)
+	var insufficientCount int
+	for i := range 300 {
+		var op Operation
+		var trans *Transition
+		var err error
+		switch {
+		case len(keys) < 3 || rng.Intn(3) == 0:
+			key := fmt.Sprintf("key-%d", nextKey)
+			nextKey++
+			op = Operation{Type: OperationInsert, Key: []byte(key), Value: fmt.Appendf(nil, "value-%d", i)}
+			trans, err = trie.InsertWithProof(op.Key, op.Value)
+		case rng.Intn(2) == 0:
+			key := keys[rng.Intn(len(keys))]
+			op = Operation{Type: OperationUpdate, Key: []byte(key), Value: fmt.Appendf(nil, "value-%d", i)}
+			trans, err = trie.UpdateWithProof(op.Key, op.Value)
+		default:
+			key := keys[rng.Intn(len(keys))]
+			op = Operation{Type: OperationDelete, Key: []byte(key)}
+			trans, err = trie.DeleteWithProof(op.Key)
+		}
+		if err != nil {
+			t.Fatalf("got unexpected error when applying %s: %s", op.Type, err)
+		}
+		// Track the keys after the change
+		switch op.Type {
+		case OperationInsert:
+			keys = append(keys, string(op.Key))
+		case OperationDelete:
+			for idx, key := range keys {
+				if key == string(op.Key) {
+					keys = append(keys[:idx], keys[idx+1:]...)
+					break
+				}
+			}
+			delete(proofs, string(op.Key))
+		}
+		insufficientCount += refreshAllProofs(t, trie, proofs, op, trans.Proof, keys)
+		if op.Type == OperationInsert {
+			proofs[string(op.Key)], err = trie.Prove(op.Key)
+			if err != nil {
+				t.Fatalf("got unexpected error when generating proof: %s", err)
+			}
+		}
+	}
+	// Make sure that the mutations cover deletes which need more than the witness
+	if insufficientCount == 0 {
+		t.Fatal("did not get any insufficient witness errors")
+	}
+}
+
+func TestProofRefreshSmallTries(t *testing.T) {
+	// Walk through tries with few keys, where branches are created and collapsed
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	proofs := map[string]*Proof{}
+	proof, err := trie.Prove([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	proofs["abcd"] = proof
+	keys := []string{"abcd"}
+	for i := range 20 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		trans, err := trie.InsertWithProof(key, []byte("value"))
+		if err != nil {
+			t.Fatalf("got unexpected error when inserting key: %s", err)
+		}
+		keys = append(keys, string(key))
+		refreshAllProofs(t, trie, proofs, Operation{Type: OperationInsert, Key: key, Value: []byte("value")}, trans.Proof, keys)
+		proofs[string(key)], err = trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+	}
+	for i := range 20 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		trans, err := trie.DeleteWithProof(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when deleting key: %s", err)
+		}
+		delete(proofs, string(key))
+		keys = slices.DeleteFunc(keys, func(tmpKey string) bool { return tmpKey == string(key) })
+		refreshAllProofs(t, trie, proofs, Operation{Type: OperationDelete, Key: key}, trans.Proof, keys)
+	}
+	if !proofs["abcd"].Verify(trie.Hash(), []byte("abcd"), []byte("1")) {
+		t.Fatal("refreshed proof did not verify")
+	}
+}
+
+func TestProofRefreshErrors(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	clientKey := []byte(fruitsTestEntries[0].key)
+	proof, err := trie.Prove(clientKey)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	// Changes to the key itself
+	witness, err := trie.Prove(clientKey)
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	if _, err := proof.Refresh(clientKey, Operation{Type: OperationInsert, Key: clientKey}, witness); err != ErrKeyExists {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyExists)
+	}
+	if _, err := proof.Refresh(clientKey, Operation{Type: OperationDelete, Key: clientKey}, witness); err != ErrKeyNotExist {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyNotExist)
+	}
+	refreshed, err := proof.Refresh(clientKey, Operation{Type: OperationUpdate, Key: clientKey, Value: []byte("new")}, witness)
+	if err != nil {
+		t.Fatalf("got unexpected error when refreshing proof: %s", err)
+	}
+	assertProofStepsEqual(t, refreshed, proof)
+	// Witness for a different trie
+	otherTrie := NewTrie()
+	otherTrie.Set([]byte("abcd"), []byte("1"))
+	otherTrie.Set([]byte("bcde"), []byte("2"))
+	otherWitness, err := otherTrie.Prove([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	if _, err := proof.Refresh(clientKey, Operation{Type: OperationUpdate, Key: []byte("abcd"), Value: []byte("2")}, otherWitness); err == nil {
+		t.Fatal("expected witness mismatch error but got nil")
+	}
+	if _, err := proof.Refresh(clientKey, Operation{Type: 0, Key: []byte("abcd")}, witness); err == nil {
+		t.Fatal("expected unknown operation error but got nil")
+	}
+}
+
+func TestProofRefreshDeleteCollapse(t *testing.T) {
+	// Find keys in different child slots of the root branch
+	var keys []string
+	slots := make(map[Nibble]bool)
+	for i := 0; len(keys) < 3; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		slot := keyToPath([]byte(key))[0]
+		if !slots[slot] {
+			slots[slot] = true
+			keys = append(keys, key)
+		}
+	}
+	trie := NewTrie()
+	for _, key := range keys {
+		trie.Set([]byte(key), []byte("value"))
+	}
+	proof, err := trie.Prove([]byte(keys[0]))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	if proof.steps[0].stepType != ProofStepTypeBranch {
+		t.Fatalf(
+			"did not get expected proof step type: got %s, expected %s",
+			proof.steps[0].stepType,
+			ProofStepTypeBranch,
+		)
+	}
+	// Deleting one of the other keys leaves a branch with a single neighbor, which the proof
+	// only knows by its hash
+	trans, err := trie.DeleteWithProof([]byte(keys[1]))
+	if err != nil {
+		t.Fatalf("got unexpected error when deleting key: %s", err)
+	}
+	op := Operation{Type: OperationDelete, Key: []byte(keys[1])}
+	if !expectInsufficientWitness(keys[0], op, []string{keys[0], keys[2]}) {
+		t.Fatal("did not expect insufficient witness for collapsed branch")
+	}
+	if _, err := proof.Refresh([]byte(keys[0]), op, trans.Proof); !errors.Is(err, ErrInsufficientWitness) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrInsufficientWitness)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// scriptDataJson is the detailed schema JSON representation of Plutus data used by
+// cardano-cli for datum and redeemer files
+type scriptDataJson struct {
+	Constructor *uint             `json:"constructor,omitempty"`
+	Fields      *[]scriptDataJson `json:"fields,omitempty"`
+	List        *[]scriptDataJson `json:"list,omitempty"`
+	Int         *json.Number      `json:"int,omitempty"`
+	Bytes       *string           `json:"bytes,omitempty"`
+}
+
+func newScriptDataConstructor(tag uint, fields ...scriptDataJson) scriptDataJson {
+	return scriptDataJson{Constructor: &tag, Fields: &fields}
+}
+
+func newScriptDataList(items []scriptDataJson) scriptDataJson {
+	return scriptDataJson{List: &items}
+}
+
+func newScriptDataInt(val int) scriptDataJson {
+	tmpVal := json.Number(strconv.Itoa(val))
+	return scriptDataJson{Int: &tmpVal}
+}
+
+func newScriptDataBytes(val []byte) scriptDataJson {
+	tmpVal := hex.EncodeToString(val)
+	return scriptDataJson{Bytes: &tmpVal}
+}
+
+// toPlutusData converts the JSON representation into a value which encodes to the equivalent
+// Plutus data CBOR
+func (d scriptDataJson) toPlutusData() (any, error) {
+	var kinds int
+	for _, present := range []bool{
+		d.Constructor != nil,
+		d.List != nil,
+		d.Int != nil,
+		d.Bytes != nil,
+	} {
+		if present {
+			kinds++
+		}
+	}
+	if kinds != 1 || (d.Fields != nil && d.Constructor == nil) {
+		return nil, errors.New("script data must have exactly one of constructor, list, int or bytes")
+	}
+	switch {
+	case d.Constructor != nil:
+		if d.Fields == nil {
+			return nil, errors.New("script data constructor missing fields")
+		}
+		tmpFields, err := scriptDataListToPlutusData(*d.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return cbor.NewConstructorEncoder(*d.Constructor, tmpFields), nil
+	case d.List != nil:
+		return scriptDataListToPlutusData(*d.List)
+	case d.Int != nil:
+		tmpVal, err := d.Int.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid script data int: %w", err)
+		}
+		return tmpVal, nil
+	default:
+		tmpVal, err := hex.DecodeString(*d.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script data bytes: %w", err)
+		}
+		return tmpVal, nil
+	}
+}
+
+func scriptDataListToPlutusData(items []scriptDataJson) ([]any, error) {
+	ret := make([]any, 0, len(items))
+	for _, item := range items {
+		tmpItem, err := item.toPlutusData()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, tmpItem)
+	}
+	return ret, nil
+}
+
+// MarshalScriptDataJSON returns the proof in the detailed schema JSON format used by
+// cardano-cli for datum and redeemer files
+func (p *Proof) MarshalScriptDataJSON() ([]byte, error) {
+	tmpSteps := make([]scriptDataJson, 0, len(p.steps))
+	for _, step := range p.steps {
+		tmpStep, err := step.scriptData()
+		if err != nil {
+			return nil, err
+		}
+		tmpSteps = append(tmpSteps, tmpStep)
+	}
+	return json.Marshal(newScriptDataList(tmpSteps))
+}
+
+// UnmarshalScriptDataJSON decodes a proof from the detailed schema JSON format used by
+// cardano-cli for datum and redeemer files
+func (p *Proof) UnmarshalScriptDataJSON(data []byte) error {
+	*p = Proof{}
+	var tmpData scriptDataJson
+	if err := json.Unmarshal(data, &tmpData); err != nil {
+		return err
+	}
+	if tmpData.List == nil {
+		return errors.New("proof script data is not a list")
+	}
+	tmpSteps := make([]ProofStep, 0, len(*tmpData.List))
+	for idx, tmpStepData := range *tmpData.List {
+		var tmpStep ProofStep
+		if err := tmpStep.unmarshalScriptData(tmpStepData); err != nil {
+			return stepError(idx, err)
+		}
+		tmpSteps = append(tmpSteps, tmpStep)
+	}
+	p.steps = tmpSteps
+	return nil
+}
+
+// MarshalScriptDataJSON returns the proof step in the detailed schema JSON format used by
+// cardano-cli for datum and redeemer files
+func (s *ProofStep) MarshalScriptDataJSON() ([]byte, error) {
+	tmpData, err := s.scriptData()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tmpData)
+}
+
+// UnmarshalScriptDataJSON decodes a proof step from the detailed schema JSON format used by
+// cardano-cli for datum and redeemer files
+func (s *ProofStep) UnmarshalScriptDataJSON(data []byte) error {
+	*s = ProofStep{}
+	var tmpData scriptDataJson
+	if err := json.Unmarshal(data, &tmpData); err != nil {
+		return err
+	}
+	return s.unmarshalScriptData(tmpData)
+}
+
+func (s *ProofStep) scriptData() (scriptDataJson, error) {
+	switch s.stepType {
+	case ProofStepTypeBranch:
+		tmpNeighbors := make([]byte, 0, len(s.neighbors)*HashSize)
+		for _, neighbor := range s.neighbors {
+			tmpNeighbors = append(tmpNeighbors, neighbor.Bytes()...)
+		}
+		return newScriptDataConstructor(
+			0,
+			newScriptDataInt(s.prefixLength),
+			newScriptDataBytes(tmpNeighbors),
+		), nil
+	case ProofStepTypeFork:
+		return newScriptDataConstructor(
+			1,
+			newScriptDataInt(s.prefixLength),
+			newScriptDataConstructor(
+				0,
+				newScriptDataInt(int(s.neighbor.nibble)),
+				newScriptDataBytes(nibblesToIndividualBytes(s.neighbor.prefix)),
+				newScriptDataBytes(s.neighbor.root.Bytes()),
+			),
+		), nil
+	case ProofStepTypeLeaf:
+		return newScriptDataConstructor(
+			2,
+			newScriptDataInt(s.prefixLength),
+			newScriptDataBytes(nibblesToBytes(s.neighbor.key)),
+			newScriptDataBytes(s.neighbor.value.Bytes()),
+		), nil
+	default:
+		return scriptDataJson{}, errors.New("unknown proof step type")
+	}
+}
+
+// unmarshalScriptData decodes the proof step by converting the script data to its Plutus data
+// CBOR, so that both formats share the same validation
+func (s *ProofStep) unmarshalScriptData(data scriptDataJson) error {
+	if data.Constructor == nil {
+		return errors.New("proof step script data is not a constructor")
+	}
+	tmpData, err := data.toPlutusData()
+	if err != nil {
+		return err
+	}
+	tmpCbor, err := cbor.Encode(tmpData)
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalCBOR(tmpCbor)
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestProofStepMarshalScriptDataJson(t *testing.T) {
+	neighborRoot := HashValue([]byte("neighbor"))
+	leafValue := HashValue([]byte("value"))
+	leafKey := keyToPath([]byte("key"))
+	testCases := []struct {
+		name     string
+		step     ProofStep
+		expected string
+	}{
+		{
+			name: "branch",
+			step: ProofStep{
+				stepType:     ProofStepTypeBranch,
+				prefixLength: 1,
+				neighbors:    []Hash{NullHash, NullHash, NullHash, neighborRoot},
+			},
+			expected: `{"constructor":0,"fields":[{"int":1},{"bytes":"` +
+				NullHash.String() + NullHash.String() + NullHash.String() + neighborRoot.String() +
+				`"}]}`,
+		},
+		{
+			name: "fork",
+			step: ProofStep{
+				stepType:     ProofStepTypeFork,
+				prefixLength: 2,
+				neighbor: ProofStepNeighbor{
+					prefix: []Nibble{0x0, 0x7, 0xa},
+					nibble: 0x3,
+					root:   neighborRoot,
+				},
+			},
+			expected: `{"constructor":1,"fields":[{"int":2},{"constructor":0,"fields":[{"int":3},{"bytes":"00070a"},{"bytes":"` +
+				neighborRoot.String() +
+				`"}]}]}`,
+		},
+		{
+			name: "leaf",
+			step: ProofStep{
+				stepType:     ProofStepTypeLeaf,
+				prefixLength: 0,
+				neighbor: ProofStepNeighbor{
+					key:   leafKey,
+					value: leafValue,
+				},
+			},
+			expected: `{"constructor":2,"fields":[{"int":0},{"bytes":"` +
+				hex.EncodeToString(nibblesToBytes(leafKey)) +
+				`"},{"bytes":"` +
+				leafValue.String() +
+				`"}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.step.MarshalScriptDataJSON()
+			if err != nil {
+				t.Fatalf("got unexpected error when encoding step: %s", err)
+			}
+			if string(got) != tc.expected {
+				t.Fatalf(
+					"did not get expected script data JSON\n  got:    %s\n  wanted: %s",
+					got,
+					tc.expected,
+				)
+			}
+			var decoded ProofStep
+			if err := decoded.UnmarshalScriptDataJSON(got); err != nil {
+				t.Fatalf("got unexpected error when decoding step: %s", err)
+			}
+			gotCbor, err := decoded.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("got unexpected error when encoding step as CBOR: %s", err)
+			}
+			wantCbor, err := tc.step.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("got unexpected error when encoding step as CBOR: %s", err)
+			}
+			if !bytes.Equal(gotCbor, wantCbor) {
+				t.Fatal("step CBOR mismatch after script data round-trip")
+			}
+		})
+	}
+}
+
+func TestProofScriptDataJsonRoundTripCbor(t *testing.T) {
+	for _, testDef := range proofTestDefs {
+		proofCbor, err := hex.DecodeString(testDef.expectedCborHex)
+		if err != nil {
+			t.Fatalf("failed to decode proof CBOR hex: %s", err)
+		}
+		var proof Proof
+		if err := proof.UnmarshalCBOR(proofCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding proof CBOR: %s", err)
+		}
+		proofJson, err := proof.MarshalScriptDataJSON()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof as script data: %s", err)
+		}
+		var decoded Proof
+		if err := decoded.UnmarshalScriptDataJSON(proofJson); err != nil {
+			t.Fatalf("got unexpected error when decoding proof script data: %s", err)
+		}
+		roundTripCbor, err := decoded.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof as CBOR: %s", err)
+		}
+		if !bytes.Equal(roundTripCbor, proofCbor) {
+			t.Fatalf(
+				"proof CBOR mismatch after script data round-trip\n  got:    %x\n  wanted: %x",
+				roundTripCbor,
+				proofCbor,
+			)
+		}
+	}
+}
+
+func TestProofScriptDataJsonEmpty(t *testing.T) {
+	proof := &Proof{}
+	got, err := proof.MarshalScriptDataJSON()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof: %s", err)
+	}
+	if string(got) != `{"list":[]}` {
+		t.Fatalf("did not get expected script data JSON: got %s", got)
+	}
+}
+
+func TestProofUnmarshalScriptDataJsonErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		json          string
+		expectedError string
+	}{
+		{
+			name:          "not a list",
+			json:          `{"int":1}`,
+			expectedError: "not a list",
+		},
+		{
+			name:          "step not a constructor",
+			json:          `{"list":[{"int":1}]}`,
+			expectedError: "not a constructor",
+		},
+		{
+			name:          "unknown constructor",
+			json:          `{"list":[{"constructor":3,"fields":[]}]}`,
+			expectedError: "unknown proof step constructor",
+		},
+		{
+			name:          "ambiguous data",
+			json:          `{"list":[{"constructor":2,"fields":[{"int":0,"bytes":"00"}]}]}`,
+			expectedError: "exactly one of",
+		},
+		{
+			name:          "negative skip",
+			json:          `{"list":[{"constructor":0,"fields":[{"int":-1},{"bytes":"00"}]}]}`,
+			expectedError: "negative value",
+		},
+		{
+			name:          "short neighbors",
+			json:          `{"list":[{"constructor":0,"fields":[{"int":0},{"bytes":"00"}]}]}`,
+			expectedError: "incorrect branch neighbor data length",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var decoded Proof
+			err := decoded.UnmarshalScriptDataJSON([]byte(tc.json))
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
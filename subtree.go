@@ -0,0 +1,238 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// SubtreeProof proves that the branch covering a path prefix has a specific merkle root of
+// children. The steps lead from the root of the trie down to that branch
+type SubtreeProof struct {
+	proof  *Proof
+	prefix []Nibble
+	root   Hash
+}
+
+// NewSubtreeProof returns a subtree proof made up of the steps leading from the root of the
+// trie to the branch, the full path to the children of the branch, and the merkle root of
+// those children
+func NewSubtreeProof(proof *Proof, prefix []Nibble, root Hash) *SubtreeProof {
+	ret := &SubtreeProof{
+		proof:  &Proof{},
+		prefix: slices.Clone(prefix),
+		root:   root,
+	}
+	if proof != nil {
+		ret.proof = NewProof(proof.steps...)
+	}
+	return ret
+}
+
+// ProveSubtree returns a proof for the branch that holds all keys whose path starts with the
+// specified prefix. Returns ErrPrefixNotExist if no key path starts with the prefix, or
+// ErrPrefixIsLeaf if only a single key path does
+func (t *Trie) ProveSubtree(pathPrefix []Nibble) (*SubtreeProof, error) {
+	if err := validateNibbles(pathPrefix); err != nil {
+		return nil, fmt.Errorf("invalid path prefix: %w", err)
+	}
+	if len(pathPrefix) > HashSize*2 {
+		return nil, fmt.Errorf("path prefix is too long: %d", len(pathPrefix))
+	}
+	return generateSubtreeProof(t.rootNode, pathPrefix)
+}
+
+// generateSubtreeProof returns the subtree proof for the node, where the path is relative to
+// the position of the node
+func generateSubtreeProof(node Node, path []Nibble) (*SubtreeProof, error) {
+	switch n := node.(type) {
+	case *Branch:
+		return n.generateSubtreeProof(path)
+	case *Leaf:
+		if len(commonPrefix(path, n.suffix)) == len(path) {
+			return nil, ErrPrefixIsLeaf
+		}
+		return nil, ErrPrefixNotExist
+	default:
+		return nil, ErrPrefixNotExist
+	}
+}
+
+// Proof returns the steps leading from the root of the trie to the branch
+func (s *SubtreeProof) Proof() *Proof {
+	return NewProof(s.proof.steps...)
+}
+
+// Prefix returns the full path to the children of the branch, including the branch prefix
+func (s *SubtreeProof) Prefix() []Nibble {
+	return slices.Clone(s.prefix)
+}
+
+// Root returns the merkle root of the children of the branch
+func (s *SubtreeProof) Root() Hash {
+	return s.root
+}
+
+// ComputeRoot returns the root hash of the trie that the proof commits to
+func (s *SubtreeProof) ComputeRoot() (Hash, error) {
+	cursors, err := s.proof.stepCursors(s.prefix)
+	if err != nil {
+		return NullHash, err
+	}
+	cursor := cursors[len(s.proof.steps)]
+	root := branchHash(s.prefix[cursor:], s.root)
+	for i := len(s.proof.steps) - 1; i >= 0; i-- {
+		root, err = s.proof.steps[i].nodeHash(s.prefix, cursors[i], root)
+		if err != nil {
+			return NullHash, stepError(i, err)
+		}
+	}
+	return root, nil
+}
+
+// Verify returns whether the proof shows that the branch covering the specified path prefix
+// is present in the trie with the specified root hash
+func (s *SubtreeProof) Verify(root Hash, pathPrefix []Nibble) bool {
+	if len(pathPrefix) > len(s.prefix) || !slices.Equal(s.prefix[:len(pathPrefix)], pathPrefix) {
+		return false
+	}
+	// The branch must start at or above the end of the prefix, otherwise it only covers
+	// part of the keys with that prefix
+	cursors, err := s.proof.stepCursors(s.prefix)
+	if err != nil || cursors[len(s.proof.steps)] > len(pathPrefix) {
+		return false
+	}
+	tmpRoot, err := s.ComputeRoot()
+	if err != nil {
+		return false
+	}
+	return tmpRoot == root
+}
+
+func (s *SubtreeProof) MarshalCBOR() ([]byte, error) {
+	proofCbor, err := s.proof.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	tmpData := []any{
+		cbor.RawMessage(proofCbor),
+		nibblesToIndividualBytes(s.prefix),
+		s.root.Bytes(),
+	}
+	return cbor.Encode(&tmpData)
+}
+
+func (s *SubtreeProof) UnmarshalCBOR(data []byte) error {
+	*s = SubtreeProof{}
+	var fields []cbor.RawMessage
+	if err := decodeExact(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return errors.New("subtree proof missing fields")
+	}
+	proof := &Proof{}
+	if err := proof.UnmarshalCBOR(fields[0]); err != nil {
+		return fmt.Errorf("invalid subtree proof steps: %w", err)
+	}
+	prefixBytes, err := decodeBytes(fields[1])
+	if err != nil {
+		return fmt.Errorf("invalid subtree proof prefix: %w", err)
+	}
+	prefix, err := subtreePrefixFromBytes(prefixBytes)
+	if err != nil {
+		return err
+	}
+	root, err := decodeHash(fields[2])
+	if err != nil {
+		return fmt.Errorf("invalid subtree proof root: %w", err)
+	}
+	s.proof = proof
+	s.prefix = prefix
+	s.root = root
+	return nil
+}
+
+// subtreeProofJson is the JSON representation of a subtree proof. The proof uses the same
+// format as Proof.MarshalJSON, and the prefix has one byte per nibble as with fork neighbor
+// prefixes
+type subtreeProofJson struct {
+	Proof  json.RawMessage `json:"proof"`
+	Prefix string          `json:"prefix"`
+	Root   string          `json:"root"`
+}
+
+func (s *SubtreeProof) MarshalJSON() ([]byte, error) {
+	proofJson, err := s.proof.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(
+		subtreeProofJson{
+			Proof:  proofJson,
+			Prefix: hex.EncodeToString(nibblesToIndividualBytes(s.prefix)),
+			Root:   s.root.String(),
+		},
+	)
+}
+
+func (s *SubtreeProof) UnmarshalJSON(data []byte) error {
+	*s = SubtreeProof{}
+	var tmpData subtreeProofJson
+	if err := json.Unmarshal(data, &tmpData); err != nil {
+		return err
+	}
+	if tmpData.Proof == nil {
+		return errors.New("subtree proof missing proof")
+	}
+	proof := &Proof{}
+	if err := proof.UnmarshalJSON(tmpData.Proof); err != nil {
+		return fmt.Errorf("invalid subtree proof steps: %w", err)
+	}
+	prefixBytes, err := hex.DecodeString(tmpData.Prefix)
+	if err != nil {
+		return fmt.Errorf("invalid subtree proof prefix: %w", err)
+	}
+	prefix, err := subtreePrefixFromBytes(prefixBytes)
+	if err != nil {
+		return err
+	}
+	root, err := hashFromHexString(tmpData.Root)
+	if err != nil {
+		return fmt.Errorf("invalid subtree proof root: %w", err)
+	}
+	s.proof = proof
+	s.prefix = prefix
+	s.root = root
+	return nil
+}
+
+// subtreePrefixFromBytes converts an encoded subtree proof prefix with one byte per nibble
+func subtreePrefixFromBytes(data []byte) ([]Nibble, error) {
+	if len(data) > HashSize*2 {
+		return nil, fmt.Errorf("subtree proof prefix is too long: %d", len(data))
+	}
+	ret, err := individualBytesToNibbles(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subtree proof prefix: %w", err)
+	}
+	return ret, nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTrieProveSubtree(t *testing.T) {
+	trie := NewTrie()
+	var keys [][]byte
+	for i := range 500 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		trie.Set(key, fmt.Appendf(nil, "value-%d", i))
+		keys = append(keys, key)
+	}
+	root := trie.Hash()
+	var prefixes [][]Nibble
+	prefixes = append(prefixes, []Nibble{})
+	for i := range 16 {
+		prefixes = append(prefixes, []Nibble{Nibble(i)})
+		for j := range 16 {
+			prefixes = append(prefixes, []Nibble{Nibble(i), Nibble(j)})
+		}
+	}
+	leafPrefixes := 0
+	for _, prefix := range prefixes {
+		// Build a trie with only the keys matching the prefix
+		subTrie := NewTrie()
+		for i, key := range keys {
+			if slices.Equal(keyToPath(key)[:len(prefix)], prefix) {
+				subTrie.Set(key, fmt.Appendf(nil, "value-%d", i))
+			}
+		}
+		proof, err := trie.ProveSubtree(prefix)
+		if subTrie.IsEmpty() {
+			if !errors.Is(err, ErrPrefixNotExist) {
+				t.Fatalf("did not get expected error: got %v, expected %v", err, ErrPrefixNotExist)
+			}
+			continue
+		}
+		if _, ok := subTrie.rootNode.(*Leaf); ok {
+			if !errors.Is(err, ErrPrefixIsLeaf) {
+				t.Fatalf("did not get expected error: got %v, expected %v", err, ErrPrefixIsLeaf)
+			}
+			leafPrefixes++
+			continue
+		}
+		if err != nil {
+			t.Fatalf("got unexpected error when generating subtree proof: %s", err)
+		}
+		if !proof.Verify(root, prefix) {
+			t.Fatalf("subtree proof did not verify for prefix %s", nibblesToHexString(prefix))
+		}
+		if proof.Verify(NullHash, prefix) {
+			t.Fatalf("subtree proof verified against wrong root for prefix %s", nibblesToHexString(prefix))
+		}
+		// The branch holds the same children as the root of the trie with only the keys
+		// under the prefix
+		if branchHash(proof.Prefix(), proof.Root()) != subTrie.Hash() {
+			t.Fatalf("subtree root does not match for prefix %s", nibblesToHexString(prefix))
+		}
+	}
+	if leafPrefixes == 0 {
+		t.Fatal("no prefixes were covered by a single leaf")
+	}
+}
+
+func TestSubtreeProofVerifyRejectsWrongPrefix(t *testing.T) {
+	trie := NewTrie()
+	for i := range 100 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), []byte("value"))
+	}
+	proof, err := trie.ProveSubtree([]Nibble{0x3})
+	if err != nil {
+		t.Fatalf("got unexpected error when generating subtree proof: %s", err)
+	}
+	fullPrefix := proof.Prefix()
+	// A longer prefix is only covered by part of the branch
+	if proof.Verify(trie.Hash(), append(fullPrefix, 0x0)) {
+		t.Fatal("subtree proof verified for prefix below the branch")
+	}
+	// A shorter prefix is also covered by other branches
+	if proof.Verify(trie.Hash(), []Nibble{}) {
+		t.Fatal("subtree proof verified for prefix above the branch")
+	}
+	if proof.Verify(trie.Hash(), []Nibble{0x4}) {
+		t.Fatal("subtree proof verified for a different prefix")
+	}
+	if _, err := trie.ProveSubtree([]Nibble{0x10}); err == nil {
+		t.Fatal("expected invalid nibble error but got nil")
+	}
+}
+
+func TestTrieProveSubtreeEmpty(t *testing.T) {
+	trie := NewTrie()
+	if _, err := trie.ProveSubtree([]Nibble{}); !errors.Is(err, ErrPrefixNotExist) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrPrefixNotExist)
+	}
+}
+
+func TestSubtreeProofEncodingRoundTrip(t *testing.T) {
+	trie := NewTrie()
+	for i := range 500 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	root := trie.Hash()
+	for _, prefix := range [][]Nibble{{}, {0x3}, {0xa, 0x1}} {
+		proof, err := trie.ProveSubtree(prefix)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating subtree proof: %s", err)
+		}
+		// A remote party can rebuild the proof from its parts
+		rebuilt := NewSubtreeProof(proof.Proof(), proof.Prefix(), proof.Root())
+		if !rebuilt.Verify(root, prefix) {
+			t.Fatalf("rebuilt subtree proof did not verify for prefix %s", nibblesToHexString(prefix))
+		}
+		proofCbor, err := proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding subtree proof as CBOR: %s", err)
+		}
+		var decodedCbor SubtreeProof
+		if err := decodedCbor.UnmarshalCBOR(proofCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding subtree proof CBOR: %s", err)
+		}
+		if !decodedCbor.Verify(root, prefix) {
+			t.Fatalf("decoded subtree proof did not verify for prefix %s", nibblesToHexString(prefix))
+		}
+		roundTripCbor, err := decodedCbor.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when re-encoding subtree proof as CBOR: %s", err)
+		}
+		if !bytes.Equal(roundTripCbor, proofCbor) {
+			t.Fatalf("round-trip subtree proof CBOR mismatch for prefix %s", nibblesToHexString(prefix))
+		}
+		proofJson, err := json.Marshal(proof)
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding subtree proof as JSON: %s", err)
+		}
+		var decodedJson SubtreeProof
+		if err := json.Unmarshal(proofJson, &decodedJson); err != nil {
+			t.Fatalf("got unexpected error when decoding subtree proof JSON: %s", err)
+		}
+		if !decodedJson.Verify(root, prefix) {
+			t.Fatalf("decoded subtree proof did not verify for prefix %s", nibblesToHexString(prefix))
+		}
+		roundTripJson, err := json.Marshal(&decodedJson)
+		if err != nil {
+			t.Fatalf("got unexpected error when re-encoding subtree proof as JSON: %s", err)
+		}
+		if !bytes.Equal(roundTripJson, proofJson) {
+			t.Fatalf("round-trip subtree proof JSON mismatch: got %s, expected %s", roundTripJson, proofJson)
+		}
+	}
+}
+
+func TestSubtreeProofUnmarshalErrors(t *testing.T) {
+	root := HashValue([]byte("root"))
+	testDefs := []struct {
+		json          string
+		expectedError string
+	}{
+		{
+			json:          `{"prefix":"","root":"` + root.String() + `"}`,
+			expectedError: "missing proof",
+		},
+		{
+			json:          `{"proof":[],"prefix":"10","root":"` + root.String() + `"}`,
+			expectedError: "out of nibble range",
+		},
+		{
+			json:          `{"proof":[],"prefix":"` + strings.Repeat("00", HashSize*2+1) + `","root":"` + root.String() + `"}`,
+			expectedError: "prefix is too long",
+		},
+		{
+			json:          `{"proof":[],"prefix":"","root":"00"}`,
+			expectedError: "invalid subtree proof root",
+		},
+	}
+	for _, testDef := range testDefs {
+		var decoded SubtreeProof
+		err := json.Unmarshal([]byte(testDef.json), &decoded)
+		if err == nil || !strings.Contains(err.Error(), testDef.expectedError) {
+			t.Fatalf("did not get expected error: got %v, expected %q", err, testDef.expectedError)
+		}
+	}
+	var decoded SubtreeProof
+	if err := decoded.UnmarshalCBOR([]byte{0x80}); err == nil {
+		t.Fatal("expected missing fields error but got nil")
+	}
+}
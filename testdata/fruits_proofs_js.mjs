@@ -0,0 +1,70 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Generates fruits_proofs_js.jsonl with the JS merkle-patricia-forestry library. The first
+// line records the library version, and each following line holds a key from the fruits
+// trie along with the output of Proof.toJSON() for it. Run from the repository root:
+//
+//   npm install --no-save @aiken-lang/merkle-patricia-forestry
+//   node testdata/fruits_proofs_js.mjs > testdata/fruits_proofs_js.jsonl
+
+import { readFileSync } from 'node:fs';
+import { Trie } from '@aiken-lang/merkle-patricia-forestry';
+
+const LIBRARY = '@aiken-lang/merkle-patricia-forestry';
+
+// The same entries as fruitsTestEntries in trie_test.go
+const FRUITS = [
+  ['apple[uid: 58]', '🍎'],
+  ['apricot[uid: 0]', '🤷'],
+  ['banana[uid: 218]', '🍌'],
+  ['blueberry[uid: 0]', '🫐'],
+  ['cherry[uid: 0]', '🍒'],
+  ['coconut[uid: 0]', '🥥'],
+  ['cranberry[uid: 0]', '🤷'],
+  ['fig[uid: 68267]', '🤷'],
+  ['grapefruit[uid: 0]', '🤷'],
+  ['grapes[uid: 0]', '🍇'],
+  ['guava[uid: 344]', '🤷'],
+  ['kiwi[uid: 0]', '🥝'],
+  ['kumquat[uid: 0]', '🤷'],
+  ['lemon[uid: 0]', '🍋'],
+  ['lime[uid: 0]', '🤷'],
+  ['mango[uid: 0]', '🥭'],
+  ['orange[uid: 0]', '🍊'],
+  ['papaya[uid: 0]', '🤷'],
+  ['passionfruit[uid: 0]', '🤷'],
+  ['peach[uid: 0]', '🍑'],
+  ['pear[uid: 0]', '🍐'],
+  ['pineapple[uid: 12577]', '🍍'],
+  ['plum[uid: 15492]', '🤷'],
+  ['pomegranate[uid: 0]', '🤷'],
+  ['raspberry[uid: 0]', '🤷'],
+  ['strawberry[uid: 2532]', '🍓'],
+  ['tangerine[uid: 11]', '🍊'],
+  ['tomato[uid: 83468]', '🍅'],
+  ['watermelon[uid: 0]', '🍉'],
+  ['yuzu[uid: 0]', '🤷'],
+];
+
+const { version } = JSON.parse(
+  readFileSync(`node_modules/${LIBRARY}/package.json`, 'utf8'),
+);
+console.log(JSON.stringify({ library: LIBRARY, version }));
+
+const trie = await Trie.fromList(FRUITS.map(([key, value]) => ({ key, value })));
+for (const [key] of FRUITS) {
+  const proof = await trie.prove(key);
+  console.log(JSON.stringify({ key, proof: proof.toJSON() }));
+}
@@ -0,0 +1,273 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+// TransitionWitness proves that applying an ordered list of operations to a trie changes
+// its root from one hash to another. It holds a proof for each operation against the trie as
+// it is when the operation is applied, which is the same proof that the on-chain insert,
+// update and delete take. Each proof leaves out the hashes that can be computed from the
+// operations and proofs before it, and steps which are identical once those hashes are left
+// out are stored only once, so the witness is smaller than the proofs on their own
+type TransitionWitness struct {
+	table *proofTable
+	// values are the hashes of the old values for the updates and deletes, leaving out
+	// those known from earlier proofs
+	values []Hash
+}
+
+// ApplyWithWitness applies the operations to the trie in order and returns the witness for
+// the resulting change of root. Each insert requires the key to be absent and each update or
+// delete requires it to be present at that point in the list. If any operation fails these
+// checks, an error is returned and the trie is left unchanged
+func (t *Trie) ApplyWithWitness(ops []Operation) (*TransitionWitness, error) {
+	// Check the operations against the keys present before changing anything
+	present := make(map[string]bool)
+	for i, op := range ops {
+		keyPresent, ok := present[string(op.Key)]
+		if !ok {
+			keyPresent = t.Has(op.Key)
+		}
+		switch op.Type {
+		case OperationInsert:
+			if keyPresent {
+				return nil, fmt.Errorf("operation %d: %w", i, ErrKeyExists)
+			}
+			present[string(op.Key)] = true
+		case OperationUpdate:
+			if !keyPresent {
+				return nil, fmt.Errorf("operation %d: %w", i, ErrKeyNotExist)
+			}
+		case OperationDelete:
+			if !keyPresent {
+				return nil, fmt.Errorf("operation %d: %w", i, ErrKeyNotExist)
+			}
+			present[string(op.Key)] = false
+		default:
+			return nil, fmt.Errorf("operation %d: unknown operation type: %d", i, op.Type)
+		}
+	}
+	ret := &TransitionWitness{
+		table: newProofTable(),
+	}
+	known := newKnownHashes()
+	for i, op := range ops {
+		path := keyToPath(op.Key)
+		var trans *Transition
+		var err error
+		switch op.Type {
+		case OperationInsert:
+			trans, err = t.InsertWithProof(op.Key, op.Value)
+		case OperationUpdate:
+			var oldValue []byte
+			oldValue, err = t.Get(op.Key)
+			if err != nil {
+				break
+			}
+			ret.addValue(known, path, HashValue(oldValue))
+			trans, err = t.UpdateWithProof(op.Key, op.Value)
+		case OperationDelete:
+			trans, err = t.DeleteWithProof(op.Key)
+			if err == nil {
+				ret.addValue(known, path, HashValue(trans.Proof.value))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if err := ret.table.add(known, path, trans.Proof); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		err = known.learn(path, trans.Proof, op.Type != OperationDelete, HashValue(op.Value))
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return ret, nil
+}
+
+// addValue records the old value hash for an update or delete unless it's already known
+func (w *TransitionWitness) addValue(known *knownHashes, path []Nibble, valueHash Hash) {
+	if _, ok := known.values[string(nibblesToIndividualBytes(path))]; !ok {
+		w.values = append(w.values, valueHash)
+	}
+}
+
+// Proofs returns the individual proofs for the operations, which must match those passed to
+// ApplyWithWitness. Each proof is checked against the root of the trie with the specified
+// root hash after the operations before it
+func (w *TransitionWitness) Proofs(oldRoot Hash, ops []Operation) ([]*Proof, error) {
+	ret, _, err := w.replay(oldRoot, ops)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ComputeRoot returns the root hash that results from applying the operations to the trie
+// with the specified root hash, as shown by the witness. The operations must match those
+// passed to ApplyWithWitness
+func (w *TransitionWitness) ComputeRoot(oldRoot Hash, ops []Operation) (Hash, error) {
+	_, ret, err := w.replay(oldRoot, ops)
+	if err != nil {
+		return NullHash, err
+	}
+	return ret, nil
+}
+
+// replay checks the proof for each operation against the root after the operations before
+// it, returning the proofs along with the final root hash
+func (w *TransitionWitness) replay(oldRoot Hash, ops []Operation) ([]*Proof, Hash, error) {
+	if len(ops) > len(w.table.proofs) {
+		return nil, NullHash, fmt.Errorf(
+			"operation %d: %w",
+			len(w.table.proofs),
+			ErrInsufficientWitness,
+		)
+	}
+	if len(ops) < len(w.table.proofs) {
+		return nil, NullHash, fmt.Errorf(
+			"operation count does not match proof count: got %d, want %d",
+			len(ops),
+			len(w.table.proofs),
+		)
+	}
+	known := newKnownHashes()
+	values := w.values
+	ret := make([]*Proof, 0, len(ops))
+	root := oldRoot
+	for i, op := range ops {
+		path := keyToPath(op.Key)
+		proof, err := w.table.proof(known, i, path)
+		if err != nil {
+			return nil, NullHash, fmt.Errorf("operation %d: %w", i, err)
+		}
+		var tmpRoot Hash
+		switch op.Type {
+		case OperationInsert:
+			tmpRoot, err = proof.excludingRoot(path)
+		case OperationUpdate, OperationDelete:
+			oldValueHash, ok := known.values[string(nibblesToIndividualBytes(path))]
+			if !ok {
+				if len(values) == 0 {
+					return nil, NullHash, fmt.Errorf("operation %d: %w", i, ErrInsufficientWitness)
+				}
+				oldValueHash = values[0]
+				values = values[1:]
+			}
+			tmpRoot, err = proof.includingRoot(path, oldValueHash)
+		default:
+			return nil, NullHash, fmt.Errorf("operation %d: unknown operation type: %d", i, op.Type)
+		}
+		if err != nil {
+			return nil, NullHash, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if tmpRoot != root {
+			return nil, NullHash, fmt.Errorf("operation %d: %w", i, ErrProofMismatch)
+		}
+		valueHash := HashValue(op.Value)
+		if op.Type == OperationDelete {
+			root, err = proof.excludingRoot(path)
+		} else {
+			root, err = proof.includingRoot(path, valueHash)
+		}
+		if err != nil {
+			return nil, NullHash, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if err := known.learn(path, proof, op.Type != OperationDelete, valueHash); err != nil {
+			return nil, NullHash, fmt.Errorf("operation %d: %w", i, err)
+		}
+		ret = append(ret, proof)
+	}
+	if len(values) > 0 {
+		return nil, NullHash, fmt.Errorf("unused old value hashes: %d", len(values))
+	}
+	return ret, root, nil
+}
+
+// VerifyTransition returns whether the witness shows that applying the operations in order
+// to the trie with root oldRoot results in the trie with root newRoot
+func VerifyTransition(
+	oldRoot Hash,
+	newRoot Hash,
+	ops []Operation,
+	witness *TransitionWitness,
+) bool {
+	if witness == nil || witness.table == nil {
+		return false
+	}
+	tmpRoot, err := witness.ComputeRoot(oldRoot, ops)
+	if err != nil {
+		return false
+	}
+	return tmpRoot == newRoot
+}
+
+// MarshalCBOR returns the CBOR encoding of the witness, which is the list of distinct steps
+// and the lists of step indexes for each proof, in the same layout as a multi-proof, followed
+// by the concatenated old value hashes
+func (w *TransitionWitness) MarshalCBOR() ([]byte, error) {
+	tmpSteps, tmpProofs := w.table.encode()
+	tmpValues := make([]byte, 0, len(w.values)*HashSize)
+	for _, tmpHash := range w.values {
+		tmpValues = append(tmpValues, tmpHash.Bytes()...)
+	}
+	tmpData := cbor.IndefLengthList{
+		tmpSteps,
+		tmpProofs,
+		tmpValues,
+	}
+	return cbor.Encode(&tmpData)
+}
+
+func (w *TransitionWitness) UnmarshalCBOR(data []byte) error {
+	*w = TransitionWitness{
+		table: newProofTable(),
+	}
+	var fields []cbor.RawMessage
+	if err := decodeExact(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return errors.New("transition witness missing fields")
+	}
+	if err := w.table.decode(fields[0], fields[1]); err != nil {
+		return fmt.Errorf("invalid transition witness: %w", err)
+	}
+	tmpValues, err := decodeBytes(fields[2])
+	if err != nil {
+		return fmt.Errorf("invalid transition witness values: %w", err)
+	}
+	if len(tmpValues)%HashSize != 0 {
+		return fmt.Errorf("invalid transition witness values length: %d", len(tmpValues))
+	}
+	if len(tmpValues)/HashSize > len(w.table.proofs) {
+		return errors.New("transition witness has more values than proofs")
+	}
+	for i := 0; i < len(tmpValues); i += HashSize {
+		tmpHash, err := hashFromBytes(tmpValues[i : i+HashSize])
+		if err != nil {
+			return err
+		}
+		w.values = append(w.values, tmpHash)
+	}
+	return nil
+}
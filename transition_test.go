@@ -0,0 +1,411 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// randomOperations returns a list of valid operations against a trie containing the
+// specified keys, updating the keys to match
+func randomOperations(rng *rand.Rand, keys map[string]bool, count int, nextKey *int) []Operation {
+	var ret []Operation
+	for i := range count {
+		var existing []string
+		for key, ok := range keys {
+			if ok {
+				existing = append(existing, key)
+			}
+		}
+		slices.Sort(existing)
+		value := fmt.Appendf(nil, "value-%d-%d", *nextKey, i)
+		switch {
+		case len(existing) == 0 || rng.Intn(3) == 0:
+			key := fmt.Sprintf("key-%d", *nextKey)
+			*nextKey++
+			keys[key] = true
+			ret = append(ret, Operation{Type: OperationInsert, Key: []byte(key), Value: value})
+		case rng.Intn(2) == 0:
+			key := existing[rng.Intn(len(existing))]
+			ret = append(ret, Operation{Type: OperationUpdate, Key: []byte(key), Value: value})
+		default:
+			key := existing[rng.Intn(len(existing))]
+			keys[key] = false
+			ret = append(ret, Operation{Type: OperationDelete, Key: []byte(key)})
+		}
+	}
+	return ret
+}
+
+func TestTrieApplyWithWitness(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	trie := NewTrie()
+	refTrie := NewTrie()
+	keys := make(map[string]bool)
+	nextKey := 0
+	for batch := range 20 {
+		// Start from an empty trie and include batches that empty it again
+		count := 1 + rng.Intn(40)
+		ops := randomOperations(rng, keys, count, &nextKey)
+		oldRoot := trie.Hash()
+		witness, err := trie.ApplyWithWitness(ops)
+		if err != nil {
+			t.Fatalf("batch %d: got unexpected error when applying operations: %s", batch, err)
+		}
+		for _, op := range ops {
+			if op.Type == OperationDelete {
+				if err := refTrie.Delete(op.Key); err != nil {
+					t.Fatalf("got unexpected error when deleting key: %s", err)
+				}
+			} else {
+				refTrie.Set(op.Key, op.Value)
+			}
+		}
+		newRoot := trie.Hash()
+		if newRoot != refTrie.Hash() {
+			t.Fatalf("batch %d: trie root does not match reference trie", batch)
+		}
+		if !VerifyTransition(oldRoot, newRoot, ops, witness) {
+			t.Fatalf("batch %d: witness did not verify", batch)
+		}
+		// The witness should survive a round trip through CBOR
+		witnessCbor, err := witness.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding witness: %s", err)
+		}
+		var tmpWitness TransitionWitness
+		if err := tmpWitness.UnmarshalCBOR(witnessCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding witness: %s", err)
+		}
+		if !VerifyTransition(oldRoot, newRoot, ops, &tmpWitness) {
+			t.Fatalf("batch %d: decoded witness did not verify", batch)
+		}
+		// Anything else should not verify
+		if VerifyTransition(newRoot, newRoot, ops, witness) && oldRoot != newRoot {
+			t.Fatalf("batch %d: witness verified with wrong old root", batch)
+		}
+		if len(ops) > 1 && VerifyTransition(oldRoot, newRoot, ops[:len(ops)-1], witness) {
+			t.Fatalf("batch %d: witness verified with missing operation", batch)
+		}
+		// Tamper with the value of an operation which isn't overwritten by a later one, so
+		// that it ends up in the new trie
+		tamperIdx := -1
+		touched := make(map[string]bool)
+		for i := len(ops) - 1; i >= 0 && tamperIdx < 0; i-- {
+			if !touched[string(ops[i].Key)] && ops[i].Type != OperationDelete {
+				tamperIdx = i
+			}
+			touched[string(ops[i].Key)] = true
+		}
+		if tamperIdx >= 0 {
+			tamperedOps := slices.Clone(ops)
+			tamperedOps[tamperIdx].Value = []byte("tampered")
+			if VerifyTransition(oldRoot, newRoot, tamperedOps, witness) {
+				t.Fatalf("batch %d: witness verified with tampered operation", batch)
+			}
+		}
+	}
+}
+
+func TestTrieApplyWithWitnessInvalidOperations(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	root := trie.Hash()
+	testDefs := []struct {
+		ops         []Operation
+		expectedErr error
+	}{
+		{
+			ops: []Operation{
+				{Type: OperationInsert, Key: []byte("bcde"), Value: []byte("2")},
+				{Type: OperationInsert, Key: []byte("bcde"), Value: []byte("3")},
+			},
+			expectedErr: ErrKeyExists,
+		},
+		{
+			ops: []Operation{
+				{Type: OperationDelete, Key: []byte("abcd")},
+				{Type: OperationUpdate, Key: []byte("abcd"), Value: []byte("2")},
+			},
+			expectedErr: ErrKeyNotExist,
+		},
+		{
+			ops: []Operation{
+				{Type: OperationUpdate, Key: []byte("cdef"), Value: []byte("2")},
+			},
+			expectedErr: ErrKeyNotExist,
+		},
+	}
+	for _, testDef := range testDefs {
+		if _, err := trie.ApplyWithWitness(testDef.ops); !errors.Is(err, testDef.expectedErr) {
+			t.Fatalf("did not get expected error: got %v, expected %v", err, testDef.expectedErr)
+		}
+		if trie.Hash() != root {
+			t.Fatalf("trie was changed by failed operations")
+		}
+	}
+}
+
+func TestTransitionWitnessSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	trie := NewTrie()
+	chainTrie := NewTrie()
+	keys := make(map[string]bool)
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		trie.Set([]byte(key), fmt.Appendf(nil, "value-%d", i))
+		chainTrie.Set([]byte(key), fmt.Appendf(nil, "value-%d", i))
+		keys[key] = true
+	}
+	// Mix inserts, updates and deletes in the order they come in
+	nextKey := 1000
+	ops := randomOperations(rng, keys, 200, &nextKey)
+	// Chain individual proofs for comparison
+	chainSize := 0
+	for _, op := range ops {
+		var trans *Transition
+		var err error
+		switch op.Type {
+		case OperationInsert:
+			trans, err = chainTrie.InsertWithProof(op.Key, op.Value)
+		case OperationUpdate:
+			trans, err = chainTrie.UpdateWithProof(op.Key, op.Value)
+		case OperationDelete:
+			trans, err = chainTrie.DeleteWithProof(op.Key)
+		}
+		if err != nil {
+			t.Fatalf("got unexpected error when applying operation: %s", err)
+		}
+		proofCbor, err := trans.Proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		// Updates and deletes also need the old value hash
+		chainSize += len(proofCbor)
+		if op.Type != OperationInsert {
+			chainSize += HashSize
+		}
+	}
+	oldRoot := trie.Hash()
+	witness, err := trie.ApplyWithWitness(ops)
+	if err != nil {
+		t.Fatalf("got unexpected error when applying operations: %s", err)
+	}
+	if trie.Hash() != chainTrie.Hash() {
+		t.Fatalf("trie root does not match chained trie")
+	}
+	if !VerifyTransition(oldRoot, trie.Hash(), ops, witness) {
+		t.Fatalf("witness did not verify")
+	}
+	witnessCbor, err := witness.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding witness: %s", err)
+	}
+	// Sharing hashes between operations should make the witness much smaller
+	if len(witnessCbor)*2 >= chainSize {
+		t.Fatalf(
+			"witness is not less than half the size of chained proofs: got %d bytes, chained proofs are %d bytes",
+			len(witnessCbor),
+			chainSize,
+		)
+	}
+}
+
+func TestTransitionWitnessRejectsTampering(t *testing.T) {
+	trie := NewTrie()
+	for i := range 100 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	ops := []Operation{
+		{Type: OperationUpdate, Key: []byte("key-1"), Value: []byte("new value")},
+		{Type: OperationDelete, Key: []byte("key-2")},
+		{Type: OperationInsert, Key: []byte("key-100"), Value: []byte("value-100")},
+	}
+	oldRoot := trie.Hash()
+	witness, err := trie.ApplyWithWitness(ops)
+	if err != nil {
+		t.Fatalf("got unexpected error when applying operations: %s", err)
+	}
+	newRoot := trie.Hash()
+	// Changing any byte of a step or old value hash breaks the link to the old root
+	for stepIdx, step := range witness.table.steps {
+		for i := range step {
+			tmpWitness := &TransitionWitness{
+				table:  cloneProofTable(witness.table),
+				values: witness.values,
+			}
+			tmpStep := slices.Clone(step)
+			tmpStep[i] ^= 0x01
+			tmpWitness.table.steps[stepIdx] = tmpStep
+			if VerifyTransition(oldRoot, newRoot, ops, tmpWitness) {
+				t.Fatalf("witness verified with tampered step %d byte %d", stepIdx, i)
+			}
+		}
+	}
+	if len(witness.values) == 0 {
+		t.Fatal("did not get expected old value hashes")
+	}
+	for i := range witness.values {
+		tmpWitness := &TransitionWitness{
+			table:  witness.table,
+			values: slices.Clone(witness.values),
+		}
+		tmpWitness.values[i] = HashValue([]byte("tampered"))
+		if VerifyTransition(oldRoot, newRoot, ops, tmpWitness) {
+			t.Fatalf("witness verified with tampered old value hash %d", i)
+		}
+	}
+	// An extra old value hash is rejected
+	extraWitness := &TransitionWitness{
+		table:  witness.table,
+		values: append(slices.Clone(witness.values), NullHash),
+	}
+	if VerifyTransition(oldRoot, newRoot, ops, extraWitness) {
+		t.Fatal("witness verified with extra old value hash")
+	}
+	// A witness which doesn't cover all of the operations is insufficient
+	extraOps := append(slices.Clone(ops), Operation{Type: OperationDelete, Key: []byte("key-3")})
+	if _, err := witness.ComputeRoot(oldRoot, extraOps); !errors.Is(err, ErrInsufficientWitness) {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrInsufficientWitness)
+	}
+	witnessCbor, err := witness.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding witness: %s", err)
+	}
+	var tmpWitness TransitionWitness
+	if err := tmpWitness.UnmarshalCBOR(witnessCbor[:len(witnessCbor)-1]); err == nil {
+		t.Fatal("expected error for truncated witness but got nil")
+	}
+	if err := tmpWitness.UnmarshalCBOR(witnessCbor); err != nil {
+		t.Fatalf("got unexpected error when decoding witness: %s", err)
+	}
+}
+
+// cloneProofTable returns a copy of the proof table whose steps can be changed without
+// affecting the original
+func cloneProofTable(table *proofTable) *proofTable {
+	return &proofTable{
+		steps:  slices.Clone(table.steps),
+		proofs: table.proofs,
+		lookup: table.lookup,
+	}
+}
+
+func TestTransitionWitnessProofs(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	chainTrie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		chainTrie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	ops := []Operation{
+		{Type: OperationUpdate, Key: []byte("apple[uid: 58]"), Value: []byte("🍏")},
+		{Type: OperationDelete, Key: []byte("kumquat[uid: 0]")},
+		{Type: OperationInsert, Key: []byte("durian[uid: 0]"), Value: []byte("🤷")},
+		{Type: OperationDelete, Key: []byte("apple[uid: 58]")},
+		{Type: OperationInsert, Key: []byte("kumquat[uid: 0]"), Value: []byte("🤷")},
+	}
+	oldRoot := trie.Hash()
+	witness, err := trie.ApplyWithWitness(ops)
+	if err != nil {
+		t.Fatalf("got unexpected error when applying operations: %s", err)
+	}
+	proofs, err := witness.Proofs(oldRoot, ops)
+	if err != nil {
+		t.Fatalf("got unexpected error when getting proofs: %s", err)
+	}
+	// Each proof should match the one for the operation on its own, and verify the same way
+	for i, op := range ops {
+		var trans *Transition
+		var oldValue []byte
+		switch op.Type {
+		case OperationInsert:
+			trans, err = chainTrie.InsertWithProof(op.Key, op.Value)
+		case OperationUpdate:
+			oldValue, _ = chainTrie.Get(op.Key)
+			trans, err = chainTrie.UpdateWithProof(op.Key, op.Value)
+		case OperationDelete:
+			oldValue, _ = chainTrie.Get(op.Key)
+			trans, err = chainTrie.DeleteWithProof(op.Key)
+		}
+		if err != nil {
+			t.Fatalf("got unexpected error when applying operation: %s", err)
+		}
+		assertProofStepsEqual(t, proofs[i], trans.Proof)
+		var ok bool
+		switch op.Type {
+		case OperationInsert:
+			ok = proofs[i].VerifyInsert(trans.OldRoot, trans.NewRoot, op.Key, op.Value)
+		case OperationUpdate:
+			ok = proofs[i].VerifyUpdate(trans.OldRoot, trans.NewRoot, op.Key, oldValue, op.Value)
+		case OperationDelete:
+			ok = proofs[i].VerifyDelete(trans.OldRoot, trans.NewRoot, op.Key, oldValue)
+		}
+		if !ok {
+			t.Fatalf("operation %d: proof did not verify", i)
+		}
+	}
+}
+
+func FuzzTransitionWitness(f *testing.F) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	ops := []Operation{
+		{Type: OperationUpdate, Key: []byte("apple[uid: 58]"), Value: []byte("🍏")},
+		{Type: OperationDelete, Key: []byte("kumquat[uid: 0]")},
+		{Type: OperationInsert, Key: []byte("durian[uid: 0]"), Value: []byte("🤷")},
+	}
+	oldRoot := trie.Hash()
+	witness, err := trie.ApplyWithWitness(ops)
+	if err != nil {
+		f.Fatalf("got unexpected error when applying operations: %s", err)
+	}
+	newRoot := trie.Hash()
+	witnessCbor, err := witness.MarshalCBOR()
+	if err != nil {
+		f.Fatalf("got unexpected error when encoding witness: %s", err)
+	}
+	f.Add(witnessCbor)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var tmpWitness TransitionWitness
+		if err := tmpWitness.UnmarshalCBOR(data); err != nil {
+			return
+		}
+		// Anything that decodes should be safe to verify, and only verify if it re-encodes
+		// to a witness which also verifies
+		if !VerifyTransition(oldRoot, newRoot, ops, &tmpWitness) {
+			return
+		}
+		tmpCbor, err := tmpWitness.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding witness: %s", err)
+		}
+		var reWitness TransitionWitness
+		if err := reWitness.UnmarshalCBOR(tmpCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding re-encoded witness: %s", err)
+		}
+		if !VerifyTransition(oldRoot, newRoot, ops, &reWitness) {
+			t.Fatal("re-encoded witness did not verify")
+		}
+	})
+}
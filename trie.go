@@ -20,14 +20,29 @@ import (
 	"strings"
 )
 
-//nolint:unused
 type Trie struct {
-	rootNode Node
-	size     int
+	rootNode   Node
+	size       int
+	proofCache *proofCache
 }
 
-func NewTrie() *Trie {
-	return &Trie{}
+// TrieOption is a function that configures a Trie
+type TrieOption func(*Trie)
+
+// Transition describes a change to a single key in a trie along with the proof that can be
+// used to verify it
+type Transition struct {
+	OldRoot Hash
+	NewRoot Hash
+	Proof   *Proof
+}
+
+func NewTrie(opts ...TrieOption) *Trie {
+	t := &Trie{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // String returns a string representation of the entire trie
@@ -53,6 +68,44 @@ func (t *Trie) String() string {
 	return ret
 }
 
+// Len returns the number of entries in the trie
+func (t *Trie) Len() int {
+	return t.size
+}
+
+// LenPrefix returns the number of entries in the trie whose key path starts with the
+// specified prefix
+func (t *Trie) LenPrefix(pathPrefix []Nibble) int {
+	if validateNibbles(pathPrefix) != nil {
+		return 0
+	}
+	node := t.rootNode
+	for node != nil {
+		switch n := node.(type) {
+		case *Leaf:
+			if len(commonPrefix(pathPrefix, n.suffix)) == len(pathPrefix) {
+				return 1
+			}
+			return 0
+		case *Branch:
+			cmnPrefix := commonPrefix(pathPrefix, n.prefix)
+			if len(cmnPrefix) == len(pathPrefix) {
+				return n.count
+			}
+			if len(cmnPrefix) < len(n.prefix) {
+				return 0
+			}
+			// Continue with the child slot for the next nibble in the prefix
+			pathPrefix = pathPrefix[len(n.prefix):]
+			node = n.children[pathPrefix[0]]
+			pathPrefix = pathPrefix[1:]
+		default:
+			panic("unknown node type...this should never happen")
+		}
+	}
+	return 0
+}
+
 // IsEmpty returns whether the trie is empty
 func (t *Trie) IsEmpty() bool {
 	return t.rootNode == nil
@@ -76,6 +129,7 @@ func (t *Trie) Set(key []byte, val []byte) {
 			val,
 		)
 		t.rootNode = l
+		t.size = 1
 		return
 	}
 	switch n := t.rootNode.(type) {
@@ -94,17 +148,20 @@ func (t *Trie) Set(key []byte, val []byte) {
 		tmpBranch.insert(path, key, val)
 		// Replace root node
 		t.rootNode = tmpBranch
+		t.size++
 	case *Branch:
 		// Determine the common prefix nibbles between existing branch and new leaf node
 		tmpPrefix := commonPrefix(path, n.prefix)
 		// Check for common prefix matching branch prefix
 		if string(tmpPrefix) == string(n.prefix) {
 			// Insert new value in existing branch
-			n.insert(
+			if n.insert(
 				path,
 				key,
 				val,
-			)
+			) {
+				t.size++
+			}
 			return
 		}
 		// Create a new branch node with the common prefix
@@ -121,6 +178,7 @@ func (t *Trie) Set(key []byte, val []byte) {
 			val,
 		)
 		t.rootNode = tmpBranch
+		t.size++
 	default:
 		panic("unknown node type...this should never happen")
 	}
@@ -137,6 +195,7 @@ func (t *Trie) Delete(key []byte) error {
 	case *Leaf:
 		if string(path) == string(n.suffix) {
 			t.rootNode = nil
+			t.size = 0
 			return nil
 		}
 		return ErrKeyNotExist
@@ -144,6 +203,7 @@ func (t *Trie) Delete(key []byte) error {
 		if err := n.delete(path); err != nil {
 			return err
 		}
+		t.size--
 		// collapse root if it now has a single child:
 		// splice the vanished branch's prefix and the child slot nibble into that child.
 		if n.size == 1 {
@@ -210,5 +270,93 @@ func (t *Trie) Prove(key []byte) (*Proof, error) {
 		return nil, ErrKeyNotExist
 	}
 	path := keyToPath(key)
-	return t.rootNode.generateProof(path)
+	return t.rootNode.generateProof(path, t.proofCache)
+}
+
+// ProveWithValueHash returns a proof for the given key along with the hash of its value, or
+// ErrKeyNotExist if the key doesn't exist in the trie. The proof does not hold a copy of
+// the value, so it can be shared to disclose membership of the key without revealing the value
+func (t *Trie) ProveWithValueHash(key []byte) (*Proof, Hash, error) {
+	proof, err := t.Prove(key)
+	if err != nil {
+		return nil, NullHash, err
+	}
+	valueHash := HashValue(proof.value)
+	proof.value = nil
+	return proof, valueHash, nil
+}
+
+// ProveAbsence returns a proof that the given key does not exist in the trie or ErrKeyExists
+// if the key exists in the trie. The proof uses the same steps as a proof for the key in a
+// trie where it has been inserted, so the root computed without the key matches Hash()
+func (t *Trie) ProveAbsence(key []byte) (*Proof, error) {
+	path := keyToPath(key)
+	if t.rootNode == nil {
+		return newProof(path, nil), nil
+	}
+	proof, err := t.rootNode.generateExclusionProof(path, t.proofCache)
+	if err != nil {
+		return nil, err
+	}
+	proof.path = path
+	return proof, nil
+}
+
+// InsertWithProof adds the specified key and value to the trie and returns the proof for the
+// change along with the roots before and after it. Returns ErrKeyExists if the key already
+// exists in the trie
+func (t *Trie) InsertWithProof(key []byte, val []byte) (*Transition, error) {
+	if t.Has(key) {
+		return nil, ErrKeyExists
+	}
+	oldRoot := t.Hash()
+	t.Set(key, val)
+	proof, err := t.Prove(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Transition{
+		OldRoot: oldRoot,
+		NewRoot: t.Hash(),
+		Proof:   proof,
+	}, nil
+}
+
+// UpdateWithProof changes the value for the specified key and returns the proof for the change
+// along with the roots before and after it. Returns ErrKeyNotExist if the key doesn't exist in
+// the trie
+func (t *Trie) UpdateWithProof(key []byte, val []byte) (*Transition, error) {
+	if !t.Has(key) {
+		return nil, ErrKeyNotExist
+	}
+	oldRoot := t.Hash()
+	t.Set(key, val)
+	proof, err := t.Prove(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Transition{
+		OldRoot: oldRoot,
+		NewRoot: t.Hash(),
+		Proof:   proof,
+	}, nil
+}
+
+// DeleteWithProof removes the specified key from the trie and returns the proof for the change
+// along with the roots before and after it. Returns ErrKeyNotExist if the key doesn't exist in
+// the trie
+func (t *Trie) DeleteWithProof(key []byte) (*Transition, error) {
+	proof, err := t.Prove(key)
+	if err != nil {
+		return nil, err
+	}
+	oldRoot := t.Hash()
+	if err := t.Delete(key); err != nil {
+		return nil, err
+	}
+	return &Transition{
+		OldRoot: oldRoot,
+		NewRoot: t.Hash(),
+		Proof:   proof,
+	}, nil
 }
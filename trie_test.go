@@ -15,6 +15,9 @@
 package mpf
 
 import (
+	"fmt"
+	"math/rand"
+	"slices"
 	"testing"
 )
 
@@ -319,3 +322,66 @@ func TestTrieFruitsSetDeleteConsistentHash(t *testing.T) {
 		}
 	}
 }
+
+func TestTrieLen(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	trie := NewTrie()
+	present := make(map[string]bool)
+	for i := range 2000 {
+		key := fmt.Sprintf("key-%d", rng.Intn(300))
+		if rng.Intn(3) == 0 {
+			err := trie.Delete([]byte(key))
+			if present[key] != (err == nil) {
+				t.Fatalf("got unexpected result when deleting key %q: %v", key, err)
+			}
+			delete(present, key)
+		} else {
+			// Sets of existing keys are updates and should not change the count
+			trie.Set([]byte(key), fmt.Appendf(nil, "value-%d", i))
+			present[key] = true
+		}
+		if trie.Len() != len(present) {
+			t.Fatalf("did not get expected length after operation %d: got %d, expected %d", i, trie.Len(), len(present))
+		}
+	}
+	// Compare prefix counts against the key paths
+	var paths [][]Nibble
+	for key := range present {
+		paths = append(paths, keyToPath([]byte(key)))
+	}
+	prefixes := [][]Nibble{{}, paths[0], paths[0][:10], {0xf, 0xf, 0xf, 0xf}}
+	for i := range 16 {
+		prefixes = append(prefixes, []Nibble{Nibble(i)})
+		for j := range 16 {
+			prefixes = append(prefixes, []Nibble{Nibble(i), Nibble(j)})
+		}
+	}
+	for _, prefix := range prefixes {
+		expected := 0
+		for _, path := range paths {
+			if slices.Equal(path[:len(prefix)], prefix) {
+				expected++
+			}
+		}
+		if count := trie.LenPrefix(prefix); count != expected {
+			t.Fatalf(
+				"did not get expected count for prefix %s: got %d, expected %d",
+				nibblesToHexString(prefix),
+				count,
+				expected,
+			)
+		}
+	}
+	if count := trie.LenPrefix([]Nibble{0x10}); count != 0 {
+		t.Fatalf("did not get expected count for invalid prefix: got %d, expected 0", count)
+	}
+	// Deleting everything leaves an empty trie
+	for key := range present {
+		if err := trie.Delete([]byte(key)); err != nil {
+			t.Fatalf("unexpected error deleting key: %s", err)
+		}
+	}
+	if trie.Len() != 0 || trie.LenPrefix(nil) != 0 {
+		t.Fatalf("did not get expected empty trie: got length %d", trie.Len())
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MalformedProofError describes a structural problem with a proof step. The field is the
+// name of the proof step field at fault, such as "prefixLength" or "neighbor.key", and is
+// empty when the problem isn't specific to a single field
+type MalformedProofError struct {
+	StepIndex int
+	Field     string
+	Reason    string
+}
+
+func (e *MalformedProofError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("proof step %d: %s", e.StepIndex, e.Reason)
+	}
+	return fmt.Sprintf("proof step %d: %s: %s", e.StepIndex, e.Field, e.Reason)
+}
+
+// newMalformedProofError returns a MalformedProofError for a problem found while processing
+// a single proof step. The step index is filled in by stepError
+func newMalformedProofError(field string, reason string, args ...any) *MalformedProofError {
+	return &MalformedProofError{
+		Field:  field,
+		Reason: fmt.Sprintf(reason, args...),
+	}
+}
+
+// stepError associates an error from processing a proof step with the index of the step
+func stepError(stepIdx int, err error) error {
+	var malformedErr *MalformedProofError
+	if errors.As(err, &malformedErr) {
+		malformedErr.StepIndex = stepIdx
+		return malformedErr
+	}
+	var fieldErr *stepFieldError
+	field := ""
+	if errors.As(err, &fieldErr) {
+		field = fieldErr.field
+	}
+	return &MalformedProofError{
+		StepIndex: stepIdx,
+		Field:     field,
+		Reason:    err.Error(),
+	}
+}
+
+// stepFieldError records the proof step field that failed to decode without changing the
+// error message
+type stepFieldError struct {
+	field string
+	err   error
+}
+
+func (e *stepFieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stepFieldError) Unwrap() error {
+	return e.err
+}
+
+// Validate checks that the proof is structurally sound, independent of the key and value
+// being proven. The prefix lengths must fit within the path, fork neighbors must fit below
+// their branch, and leaf neighbor keys must agree with each other on the path to each branch
+// while diverging from it at the branch nibble. Returns a *MalformedProofError describing the
+// first problem found
+func (p *Proof) Validate() error {
+	nextCursors := make([]int, 0, len(p.steps))
+	cursor := 0
+	for i := range p.steps {
+		step := &p.steps[i]
+		if err := step.validate(cursor); err != nil {
+			return stepError(i, err)
+		}
+		cursor += 1 + step.prefixLength
+		nextCursors = append(nextCursors, cursor)
+	}
+	// Leaf neighbor keys share the path up to the nibble for their branch, which gives the
+	// path nibbles down to the deepest leaf step
+	var knownPath []Nibble
+	for i := range p.steps {
+		step := &p.steps[i]
+		if step.stepType != ProofStepTypeLeaf {
+			continue
+		}
+		prefixLen := nextCursors[i] - 1
+		for j := range min(prefixLen, len(knownPath)) {
+			if step.neighbor.key[j] != knownPath[j] {
+				return &MalformedProofError{
+					StepIndex: i,
+					Field:     "neighbor.key",
+					Reason:    fmt.Sprintf("does not match the path at nibble %d", j),
+				}
+			}
+		}
+		if prefixLen > len(knownPath) {
+			knownPath = step.neighbor.key[:prefixLen]
+		}
+	}
+	// Each neighbor must sit in a different child slot than the path
+	for i := range p.steps {
+		step := &p.steps[i]
+		branchIdx := nextCursors[i] - 1
+		if branchIdx >= len(knownPath) {
+			break
+		}
+		switch step.stepType {
+		case ProofStepTypeFork:
+			if step.neighbor.nibble == knownPath[branchIdx] {
+				return &MalformedProofError{
+					StepIndex: i,
+					Field:     "neighbor.nibble",
+					Reason:    fmt.Sprintf("collides with the path nibble %s", knownPath[branchIdx]),
+				}
+			}
+		case ProofStepTypeLeaf:
+			if step.neighbor.key[branchIdx] == knownPath[branchIdx] {
+				return &MalformedProofError{
+					StepIndex: i,
+					Field:     "neighbor.key",
+					Reason:    fmt.Sprintf("does not diverge from the path at nibble %d", branchIdx),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks the proof step on its own, given the path position at which it starts
+func (s *ProofStep) validate(cursor int) error {
+	if s.prefixLength < 0 {
+		return newMalformedProofError("prefixLength", "negative prefix length: %d", s.prefixLength)
+	}
+	nextCursor := cursor + 1 + s.prefixLength
+	if nextCursor > HashSize*2 {
+		return newMalformedProofError(
+			"prefixLength",
+			"prefix length %d exceeds remaining path",
+			s.prefixLength,
+		)
+	}
+	switch s.stepType {
+	case ProofStepTypeBranch:
+		if len(s.neighbors) != branchProofNeighborCount {
+			return newMalformedProofError(
+				"neighbors",
+				"incorrect branch neighbor count: got %d, want %d",
+				len(s.neighbors),
+				branchProofNeighborCount,
+			)
+		}
+	case ProofStepTypeFork:
+		if err := validateNibbles([]Nibble{s.neighbor.nibble}); err != nil {
+			return newMalformedProofError("neighbor.nibble", "%s", err)
+		}
+		if err := validateNibbles(s.neighbor.prefix); err != nil {
+			return newMalformedProofError("neighbor.prefix", "%s", err)
+		}
+		// The neighbor branch needs room for its own child slot nibble after its prefix
+		if nextCursor+len(s.neighbor.prefix) >= HashSize*2 {
+			return newMalformedProofError(
+				"neighbor.prefix",
+				"prefix length %d exceeds remaining path",
+				len(s.neighbor.prefix),
+			)
+		}
+	case ProofStepTypeLeaf:
+		if len(s.neighbor.key) != HashSize*2 {
+			return newMalformedProofError(
+				"neighbor.key",
+				"incorrect leaf neighbor key length: got %d, want %d",
+				len(s.neighbor.key),
+				HashSize*2,
+			)
+		}
+		if err := validateNibbles(s.neighbor.key); err != nil {
+			return newMalformedProofError("neighbor.key", "%s", err)
+		}
+	default:
+		return newMalformedProofError("type", "unknown proof step type: %d", s.stepType)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pathWithNibbles returns a key path starting with the specified nibbles
+func pathWithNibbles(nibbles ...Nibble) []Nibble {
+	ret := keyToPath([]byte("path"))
+	copy(ret, nibbles)
+	return ret
+}
+
+// assertMalformedProofError checks that the error is a MalformedProofError for the specified
+// step and field
+func assertMalformedProofError(t *testing.T, err error, stepIdx int, field string) {
+	t.Helper()
+	var malformedErr *MalformedProofError
+	if !errors.As(err, &malformedErr) {
+		t.Fatalf("did not get expected MalformedProofError: got %v", err)
+	}
+	if malformedErr.StepIndex != stepIdx || malformedErr.Field != field {
+		t.Fatalf(
+			"did not get expected error location: got step %d field %q, expected step %d field %q (%s)",
+			malformedErr.StepIndex,
+			malformedErr.Field,
+			stepIdx,
+			field,
+			malformedErr,
+		)
+	}
+}
+
+func TestProofValidateTrieProofs(t *testing.T) {
+	trie := NewTrie()
+	for i := range 300 {
+		trie.Set(fmt.Appendf(nil, "key-%d", i), fmt.Appendf(nil, "value-%d", i))
+	}
+	for i := range 400 {
+		key := fmt.Appendf(nil, "key-%d", i)
+		var proof *Proof
+		var err error
+		if i < 300 {
+			proof, err = trie.Prove(key)
+		} else {
+			proof, err = trie.ProveAbsence(key)
+		}
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		if err := proof.Validate(); err != nil {
+			t.Fatalf("got unexpected error when validating proof for key %q: %s", key, err)
+		}
+	}
+}
+
+func TestProofValidateErrors(t *testing.T) {
+	branchStep, err := NewBranchStep(0, make([]Hash, branchProofNeighborCount))
+	if err != nil {
+		t.Fatalf("got unexpected error when creating branch step: %s", err)
+	}
+	newLeafStep := func(prefixLength int, key []Nibble) ProofStep {
+		step, err := NewLeafStep(prefixLength, key, NullHash)
+		if err != nil {
+			t.Fatalf("got unexpected error when creating leaf step: %s", err)
+		}
+		return step
+	}
+	newForkStep := func(prefixLength int, nibble Nibble, prefix []Nibble) ProofStep {
+		step, err := NewForkStep(prefixLength, nibble, prefix, NullHash)
+		if err != nil {
+			t.Fatalf("got unexpected error when creating fork step: %s", err)
+		}
+		return step
+	}
+	longBranchStep := branchStep
+	longBranchStep.prefixLength = HashSize * 2
+	testDefs := []struct {
+		name    string
+		steps   []ProofStep
+		stepIdx int
+		field   string
+	}{
+		{
+			name:    "unknown step type",
+			steps:   []ProofStep{branchStep, {}},
+			stepIdx: 1,
+			field:   "type",
+		},
+		{
+			name: "branch neighbor count",
+			steps: []ProofStep{
+				{stepType: ProofStepTypeBranch, neighbors: make([]Hash, 3)},
+			},
+			stepIdx: 0,
+			field:   "neighbors",
+		},
+		{
+			name:    "prefix length exceeds path",
+			steps:   []ProofStep{branchStep, longBranchStep},
+			stepIdx: 1,
+			field:   "prefixLength",
+		},
+		{
+			name:    "fork prefix exceeds path",
+			steps:   []ProofStep{newForkStep(0, 1, make([]Nibble, HashSize*2-1))},
+			stepIdx: 0,
+			field:   "neighbor.prefix",
+		},
+		{
+			name: "leaf neighbor key length",
+			steps: []ProofStep{
+				{stepType: ProofStepTypeLeaf, neighbor: ProofStepNeighbor{key: []Nibble{1, 2}}},
+			},
+			stepIdx: 0,
+			field:   "neighbor.key",
+		},
+		{
+			name: "leaf neighbor keys disagree on path",
+			steps: []ProofStep{
+				newLeafStep(0, pathWithNibbles(1)),
+				newLeafStep(0, pathWithNibbles(2, 3)),
+				newLeafStep(0, pathWithNibbles(3, 4, 5)),
+			},
+			stepIdx: 2,
+			field:   "neighbor.key",
+		},
+		{
+			name: "fork neighbor collides with path",
+			steps: []ProofStep{
+				newForkStep(0, 4, nil),
+				newLeafStep(0, pathWithNibbles(4, 5)),
+			},
+			stepIdx: 0,
+			field:   "neighbor.nibble",
+		},
+		{
+			name: "leaf neighbor collides with path",
+			steps: []ProofStep{
+				branchStep,
+				newLeafStep(0, pathWithNibbles(6, 8)),
+				newLeafStep(0, pathWithNibbles(6, 8, 9)),
+			},
+			stepIdx: 1,
+			field:   "neighbor.key",
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			proof := &Proof{steps: testDef.steps}
+			assertMalformedProofError(t, proof.Validate(), testDef.stepIdx, testDef.field)
+		})
+	}
+}
+
+func TestProofUnmarshalCborMalformedError(t *testing.T) {
+	branchStep, err := NewBranchStep(0, make([]Hash, branchProofNeighborCount))
+	if err != nil {
+		t.Fatalf("got unexpected error when creating branch step: %s", err)
+	}
+	proofCbor, err := NewProof(branchStep, branchStep).MarshalCBOR()
+	if err != nil {
+		t.Fatalf("got unexpected error when encoding proof: %s", err)
+	}
+	// Replace the prefix length of the second step with -1
+	stepIdx := bytes.LastIndex(proofCbor, []byte{0xd8, 0x79, 0x9f, 0x00})
+	proofCbor[stepIdx+3] = 0x20
+	var proof Proof
+	err = proof.UnmarshalCBOR(proofCbor)
+	assertMalformedProofError(t, err, 1, "prefixLength")
+	if !strings.Contains(err.Error(), "negative value") {
+		t.Fatalf("did not get expected error message: got %s", err)
+	}
+}
+
+func TestProofVerifyMalformedError(t *testing.T) {
+	key := []byte("abcd")
+	path := keyToPath(key)
+	forkStep, err := NewForkStep(0, path[0], nil, NullHash)
+	if err != nil {
+		t.Fatalf("got unexpected error when creating fork step: %s", err)
+	}
+	_, err = NewProof(forkStep).ComputeRoot(key, []byte("1"))
+	assertMalformedProofError(t, err, 0, "neighbor.nibble")
+	branchStep, err := NewBranchStep(HashSize*2, make([]Hash, branchProofNeighborCount))
+	if err != nil {
+		t.Fatalf("got unexpected error when creating branch step: %s", err)
+	}
+	_, err = NewProof(forkStep, branchStep).ComputeExclusionRoot(key)
+	assertMalformedProofError(t, err, 1, "prefixLength")
+}
@@ -0,0 +1,337 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ComputeRoot returns the root hash of the trie that the proof commits to, assuming that
+// the specified key and value are present in it
+func (p *Proof) ComputeRoot(key []byte, value []byte) (Hash, error) {
+	return p.ComputeRootWithValueHash(key, HashValue(value))
+}
+
+// ComputeRootWithValueHash returns the root hash of the trie that the proof commits to,
+// assuming that the specified key is present in it with a value matching the specified hash
+func (p *Proof) ComputeRootWithValueHash(key []byte, valueHash Hash) (Hash, error) {
+	return p.includingRoot(keyToPath(key), valueHash)
+}
+
+// Verify returns whether the proof shows that the specified key and value are present in
+// the trie with the specified root hash
+func (p *Proof) Verify(root Hash, key []byte, value []byte) bool {
+	tmpRoot, err := p.ComputeRoot(key, value)
+	if err != nil {
+		return false
+	}
+	return tmpRoot == root
+}
+
+// VerifyWithValueHash returns whether the proof shows that the specified key is present in
+// the trie with the specified root hash with a value matching the specified hash
+func (p *Proof) VerifyWithValueHash(root Hash, key []byte, valueHash Hash) bool {
+	tmpRoot, err := p.ComputeRootWithValueHash(key, valueHash)
+	if err != nil {
+		return false
+	}
+	return tmpRoot == root
+}
+
+// ComputeExclusionRoot returns the root hash of the trie that the proof commits to, assuming
+// that the specified key is not present in it
+func (p *Proof) ComputeExclusionRoot(key []byte) (Hash, error) {
+	return p.excludingRoot(keyToPath(key))
+}
+
+// VerifyExclusion returns whether the proof shows that the specified key is not present in
+// the trie with the specified root hash
+func (p *Proof) VerifyExclusion(root Hash, key []byte) bool {
+	tmpRoot, err := p.ComputeExclusionRoot(key)
+	if err != nil {
+		return false
+	}
+	return tmpRoot == root
+}
+
+// VerifyInsert returns whether the proof shows that inserting the specified key and value
+// into the trie with root oldRoot results in the trie with root newRoot
+func (p *Proof) VerifyInsert(
+	oldRoot Hash,
+	newRoot Hash,
+	key []byte,
+	value []byte,
+) bool {
+	path := keyToPath(key)
+	tmpOldRoot, err := p.excludingRoot(path)
+	if err != nil || tmpOldRoot != oldRoot {
+		return false
+	}
+	tmpNewRoot, err := p.includingRoot(path, HashValue(value))
+	if err != nil {
+		return false
+	}
+	return tmpNewRoot == newRoot
+}
+
+// VerifyDelete returns whether the proof shows that removing the specified key and value
+// from the trie with root oldRoot results in the trie with root newRoot
+func (p *Proof) VerifyDelete(
+	oldRoot Hash,
+	newRoot Hash,
+	key []byte,
+	value []byte,
+) bool {
+	return p.VerifyInsert(newRoot, oldRoot, key, value)
+}
+
+// VerifyUpdate returns whether the proof shows that changing the value for the specified
+// key from oldValue to newValue in the trie with root oldRoot results in the trie with root
+// newRoot
+func (p *Proof) VerifyUpdate(
+	oldRoot Hash,
+	newRoot Hash,
+	key []byte,
+	oldValue []byte,
+	newValue []byte,
+) bool {
+	path := keyToPath(key)
+	tmpOldRoot, err := p.includingRoot(path, HashValue(oldValue))
+	if err != nil || tmpOldRoot != oldRoot {
+		return false
+	}
+	tmpNewRoot, err := p.includingRoot(path, HashValue(newValue))
+	if err != nil {
+		return false
+	}
+	return tmpNewRoot == newRoot
+}
+
+// includingRoot walks the proof steps from the leaf for the specified path back up to the
+// root, returning the resulting root hash
+func (p *Proof) includingRoot(path []Nibble, valueHash Hash) (Hash, error) {
+	cursors, err := p.stepCursors(path)
+	if err != nil {
+		return NullHash, err
+	}
+	return p.subtreeHash(path, cursors, 0, true, valueHash)
+}
+
+// excludingRoot walks the proof steps for the specified path back up to the root as if the
+// target leaf were removed, returning the resulting root hash
+func (p *Proof) excludingRoot(path []Nibble) (Hash, error) {
+	cursors, err := p.stepCursors(path)
+	if err != nil {
+		return NullHash, err
+	}
+	return p.subtreeHash(path, cursors, 0, false, NullHash)
+}
+
+// subtreeHash walks the proof steps back up to the step at fromIdx, returning the hash of
+// the node at the position where that step starts. The target leaf is included with the
+// specified value hash or, when include is false, treated as removed
+func (p *Proof) subtreeHash(
+	path []Nibble,
+	cursors []int,
+	fromIdx int,
+	include bool,
+	valueHash Hash,
+) (Hash, error) {
+	lastIdx := len(p.steps) - 1
+	var root Hash
+	if include {
+		root = leafHash(path[cursors[len(p.steps)]:], valueHash)
+	} else {
+		if fromIdx > lastIdx {
+			return NullHash, nil
+		}
+		var err error
+		root, err = p.steps[lastIdx].excludedHash(path, cursors[lastIdx])
+		if err != nil {
+			return NullHash, stepError(lastIdx, err)
+		}
+		lastIdx--
+	}
+	for i := lastIdx; i >= fromIdx; i-- {
+		var err error
+		root, err = p.steps[i].nodeHash(path, cursors[i], root)
+		if err != nil {
+			return NullHash, stepError(i, err)
+		}
+	}
+	return root, nil
+}
+
+// stepCursors returns the path position at which each proof step starts. The extra
+// trailing entry is the position at which the target leaf suffix starts
+func (p *Proof) stepCursors(path []Nibble) ([]int, error) {
+	ret := make([]int, 0, len(p.steps)+1)
+	cursor := 0
+	for i, step := range p.steps {
+		ret = append(ret, cursor)
+		cursor += 1 + step.prefixLength
+		if cursor > len(path) {
+			return nil, &MalformedProofError{
+				StepIndex: i,
+				Field:     "prefixLength",
+				Reason:    fmt.Sprintf("prefix length %d exceeds remaining path", step.prefixLength),
+			}
+		}
+	}
+	ret = append(ret, cursor)
+	return ret, nil
+}
+
+// nodeHash returns the hash of the branch node described by the proof step, given the
+// path being proven, the position at which the branch starts, and the hash of the child
+// node containing the rest of the path
+func (s *ProofStep) nodeHash(
+	path []Nibble,
+	cursor int,
+	childHash Hash,
+) (Hash, error) {
+	childrenRoot, err := s.childrenRoot(path, cursor, childHash)
+	if err != nil {
+		return NullHash, err
+	}
+	nextCursor := cursor + 1 + s.prefixLength
+	return branchHash(path[cursor:nextCursor-1], childrenRoot), nil
+}
+
+// excludedHash returns the hash of the node that takes the place of the branch described by
+// the final proof step once the target leaf is removed
+func (s *ProofStep) excludedHash(path []Nibble, cursor int) (Hash, error) {
+	switch s.stepType {
+	case ProofStepTypeBranch:
+		// The branch keeps its other children and the target slot becomes empty
+		return s.nodeHash(path, cursor, NullHash)
+	case ProofStepTypeFork:
+		// The branch collapses into its only other child, which absorbs the branch prefix
+		// and the child slot nibble
+		prefix := slices.Concat(
+			path[cursor:cursor+s.prefixLength],
+			[]Nibble{s.neighbor.nibble},
+			s.neighbor.prefix,
+		)
+		return branchHash(prefix, s.neighbor.root), nil
+	case ProofStepTypeLeaf:
+		// The branch collapses into the neighbor leaf, whose suffix now starts at the
+		// branch position
+		if len(s.neighbor.key) < cursor {
+			return NullHash, newMalformedProofError("neighbor.key", "leaf neighbor key is too short")
+		}
+		return leafHash(s.neighbor.key[cursor:], s.neighbor.value), nil
+	default:
+		return NullHash, newMalformedProofError("type", "unknown proof step type: %d", s.stepType)
+	}
+}
+
+// childrenRoot returns the merkle root of the children of the branch node described by
+// the proof step
+func (s *ProofStep) childrenRoot(
+	path []Nibble,
+	cursor int,
+	childHash Hash,
+) (Hash, error) {
+	nextCursor := cursor + 1 + s.prefixLength
+	if nextCursor > len(path) {
+		return NullHash, newMalformedProofError("prefixLength", "prefix length exceeds remaining path")
+	}
+	childIdx := path[nextCursor-1]
+	switch s.stepType {
+	case ProofStepTypeBranch:
+		if len(s.neighbors) != branchProofNeighborCount {
+			return NullHash, newMalformedProofError(
+				"neighbors",
+				"incorrect branch neighbor count: got %d, want %d",
+				len(s.neighbors),
+				branchProofNeighborCount,
+			)
+		}
+		return merkleProofRoot(int(childIdx), childHash, s.neighbors), nil
+	case ProofStepTypeFork:
+		if s.neighbor.nibble == childIdx {
+			return NullHash, newMalformedProofError(
+				"neighbor.nibble",
+				"fork neighbor nibble collides with path: %s",
+				childIdx,
+			)
+		}
+		neighborHash := branchHash(s.neighbor.prefix, s.neighbor.root)
+		return sparseMerkleRoot(
+			int(childIdx),
+			childHash,
+			int(s.neighbor.nibble),
+			neighborHash,
+		), nil
+	case ProofStepTypeLeaf:
+		if len(s.neighbor.key) < nextCursor {
+			return NullHash, newMalformedProofError("neighbor.key", "leaf neighbor key is too short")
+		}
+		neighborIdx := s.neighbor.key[nextCursor-1]
+		if neighborIdx == childIdx {
+			return NullHash, newMalformedProofError(
+				"neighbor.key",
+				"leaf neighbor nibble collides with path: %s",
+				childIdx,
+			)
+		}
+		neighborHash := leafHash(s.neighbor.key[nextCursor:], s.neighbor.value)
+		return sparseMerkleRoot(
+			int(childIdx),
+			childHash,
+			int(neighborIdx),
+			neighborHash,
+		), nil
+	default:
+		return NullHash, newMalformedProofError("type", "unknown proof step type: %d", s.stepType)
+	}
+}
+
+// merkleProofRoot calculates the merkle root of the 16 children of a branch from the hash
+// of the child at the specified index and the neighbor hashes generated by merkleProof
+func merkleProofRoot(idx int, childHash Hash, neighbors []Hash) Hash {
+	return merkleGroupRoot(idx, childHash, neighbors, 0)
+}
+
+// merkleGroupRoot calculates the merkle root of the group of children containing the child
+// at the specified index whose size matches the neighbor at the specified level. Level 0 is
+// the group of all 16 children, and each following level halves the group size
+func merkleGroupRoot(idx int, childHash Hash, neighbors []Hash, level int) Hash {
+	ret := childHash
+	for tmpLevel := len(neighbors) - 1; tmpLevel >= level; tmpLevel-- {
+		if idx&(8>>tmpLevel) != 0 {
+			ret = HashValue(append(neighbors[tmpLevel].Bytes(), ret.Bytes()...))
+		} else {
+			ret = HashValue(append(ret.Bytes(), neighbors[tmpLevel].Bytes()...))
+		}
+	}
+	return ret
+}
+
+// sparseMerkleRoot calculates the merkle root of the 16 children of a branch which has
+// only the two specified children
+func sparseMerkleRoot(
+	idxA int,
+	hashA Hash,
+	idxB int,
+	hashB Hash,
+) Hash {
+	var tmpHashes [16]Hash
+	tmpHashes[idxA] = hashA
+	tmpHashes[idxB] = hashB
+	return merkleRootHashes(tmpHashes[:])
+}
@@ -0,0 +1,339 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mpf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestProofVerifyFruits(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	root := trie.Hash()
+	for _, entry := range fruitsTestEntries {
+		proof, err := trie.Prove([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		tmpRoot, err := proof.ComputeRoot([]byte(entry.key), []byte(entry.value))
+		if err != nil {
+			t.Fatalf("got unexpected error when computing root: %s", err)
+		}
+		if tmpRoot != root {
+			t.Fatalf(
+				"did not get expected root for key %q\n  got:    %s\n  wanted: %s",
+				entry.key,
+				tmpRoot.String(),
+				root.String(),
+			)
+		}
+		if !proof.Verify(root, []byte(entry.key), []byte(entry.value)) {
+			t.Fatalf("proof did not verify for key %q", entry.key)
+		}
+		if proof.Verify(root, []byte(entry.key), []byte("wrong value")) {
+			t.Fatalf("proof verified with wrong value for key %q", entry.key)
+		}
+		if proof.Verify(root, []byte("wrong key"), []byte(entry.value)) {
+			t.Fatalf("proof verified with wrong key for key %q", entry.key)
+		}
+		if proof.Verify(NullHash, []byte(entry.key), []byte(entry.value)) {
+			t.Fatalf("proof verified against wrong root for key %q", entry.key)
+		}
+	}
+}
+
+func TestProofVerifyDecodedCbor(t *testing.T) {
+	root, err := hex.DecodeString(fruitsExpectedHash)
+	if err != nil {
+		t.Fatalf("failed to decode root hash: %s", err)
+	}
+	rootHash, err := hashFromBytes(root)
+	if err != nil {
+		t.Fatalf("failed to decode root hash: %s", err)
+	}
+	for _, testDef := range proofTestDefs {
+		proofCbor, err := hex.DecodeString(testDef.expectedCborHex)
+		if err != nil {
+			t.Fatalf("failed to decode proof CBOR hex: %s", err)
+		}
+		var proof Proof
+		if err := proof.UnmarshalCBOR(proofCbor); err != nil {
+			t.Fatalf("got unexpected error when decoding proof CBOR: %s", err)
+		}
+		var value []byte
+		for _, entry := range fruitsTestEntries {
+			if entry.key == string(testDef.key) {
+				value = []byte(entry.value)
+			}
+		}
+		if !proof.Verify(rootHash, testDef.key, value) {
+			t.Fatalf("decoded proof did not verify for key %q", testDef.key)
+		}
+	}
+}
+
+func TestProofVerifySingleLeaf(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	proof, err := trie.Prove([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating proof: %s", err)
+	}
+	if !proof.Verify(trie.Hash(), []byte("abcd"), []byte("1")) {
+		t.Fatal("proof did not verify for single leaf trie")
+	}
+}
+
+func TestProofVerifyRandomTries(t *testing.T) {
+	for _, size := range []int{2, 3, 17, 100, 500} {
+		trie := NewTrie()
+		for i := range size {
+			trie.Set(
+				fmt.Appendf(nil, "key-%d", i),
+				fmt.Appendf(nil, "value-%d", i),
+			)
+		}
+		root := trie.Hash()
+		for i := range size {
+			key := fmt.Appendf(nil, "key-%d", i)
+			proof, err := trie.Prove(key)
+			if err != nil {
+				t.Fatalf("got unexpected error when generating proof: %s", err)
+			}
+			if !proof.Verify(root, key, fmt.Appendf(nil, "value-%d", i)) {
+				t.Fatalf("proof did not verify for key %q in trie of size %d", key, size)
+			}
+		}
+	}
+}
+
+func TestProofVerifyWithValueHash(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	root := trie.Hash()
+	for _, entry := range fruitsTestEntries {
+		proof, valueHash, err := trie.ProveWithValueHash([]byte(entry.key))
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		if valueHash != HashValue([]byte(entry.value)) {
+			t.Fatalf("did not get expected value hash for key %q", entry.key)
+		}
+		if proof.value != nil {
+			t.Fatalf("proof contains value for key %q", entry.key)
+		}
+		if !proof.VerifyWithValueHash(root, []byte(entry.key), valueHash) {
+			t.Fatalf("proof did not verify with value hash for key %q", entry.key)
+		}
+		if proof.VerifyWithValueHash(root, []byte(entry.key), HashValue([]byte("wrong value"))) {
+			t.Fatalf("proof verified with wrong value hash for key %q", entry.key)
+		}
+		// The value hash proof is the same as the regular proof
+		if !proof.Verify(root, []byte(entry.key), []byte(entry.value)) {
+			t.Fatalf("proof did not verify with value for key %q", entry.key)
+		}
+	}
+	if _, _, err := trie.ProveWithValueHash([]byte("missing")); err != ErrKeyNotExist {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyNotExist)
+	}
+}
+
+func TestProofComputeRootRejectsForkCollision(t *testing.T) {
+	key := []byte("abcd")
+	path := keyToPath(key)
+	proof := &Proof{
+		steps: []ProofStep{
+			{
+				stepType:     ProofStepTypeFork,
+				prefixLength: 0,
+				neighbor: ProofStepNeighbor{
+					prefix: []Nibble{},
+					nibble: path[0],
+					root:   HashValue([]byte("neighbor")),
+				},
+			},
+		},
+	}
+	if _, err := proof.ComputeRoot(key, []byte("1")); err == nil {
+		t.Fatal("expected fork collision error but got nil")
+	}
+}
+
+func TestProofComputeRootRejectsOverlongPrefix(t *testing.T) {
+	proof := &Proof{
+		steps: []ProofStep{
+			{
+				stepType:     ProofStepTypeBranch,
+				prefixLength: 64,
+				neighbors:    make([]Hash, branchProofNeighborCount),
+			},
+		},
+	}
+	if _, err := proof.ComputeRoot([]byte("abcd"), []byte("1")); err == nil {
+		t.Fatal("expected prefix length error but got nil")
+	}
+}
+
+func TestTrieProveAbsence(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	root := trie.Hash()
+	for i := range 50 {
+		key := fmt.Appendf(nil, "missing-%d", i)
+		proof, err := trie.ProveAbsence(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating exclusion proof: %s", err)
+		}
+		if !proof.VerifyExclusion(root, key) {
+			t.Fatalf("exclusion proof did not verify for key %q", key)
+		}
+		if proof.VerifyExclusion(root, []byte(fruitsTestEntries[0].key)) {
+			t.Fatalf("exclusion proof verified for wrong key")
+		}
+		// The exclusion proof should match the inclusion proof once the key is added
+		proofCbor, err := proof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		trie.Set(key, []byte("value"))
+		inclusionProof, err := trie.Prove(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when generating proof: %s", err)
+		}
+		inclusionCbor, err := inclusionProof.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("got unexpected error when encoding proof: %s", err)
+		}
+		if hex.EncodeToString(proofCbor) != hex.EncodeToString(inclusionCbor) {
+			t.Fatalf(
+				"exclusion proof does not match inclusion proof after insert\n  got:    %x\n  wanted: %x",
+				proofCbor,
+				inclusionCbor,
+			)
+		}
+		if !proof.Verify(trie.Hash(), key, []byte("value")) {
+			t.Fatalf("exclusion proof did not verify as inclusion proof after insert")
+		}
+		if err := trie.Delete(key); err != nil {
+			t.Fatalf("got unexpected error when deleting key: %s", err)
+		}
+	}
+}
+
+func TestTrieProveAbsenceExistingKey(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	_, err := trie.ProveAbsence([]byte(fruitsTestEntries[3].key))
+	if err != ErrKeyExists {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyExists)
+	}
+}
+
+func TestTrieProveAbsenceSmallTries(t *testing.T) {
+	trie := NewTrie()
+	proof, err := trie.ProveAbsence([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating exclusion proof: %s", err)
+	}
+	if !proof.VerifyExclusion(trie.Hash(), []byte("abcd")) {
+		t.Fatal("exclusion proof did not verify for empty trie")
+	}
+	trie.Set([]byte("bcde"), []byte("1"))
+	proof, err = trie.ProveAbsence([]byte("abcd"))
+	if err != nil {
+		t.Fatalf("got unexpected error when generating exclusion proof: %s", err)
+	}
+	if !proof.VerifyExclusion(trie.Hash(), []byte("abcd")) {
+		t.Fatal("exclusion proof did not verify for single leaf trie")
+	}
+}
+
+func TestProofVerifyTransitions(t *testing.T) {
+	trie := NewTrie()
+	for _, entry := range fruitsTestEntries {
+		trie.Set([]byte(entry.key), []byte(entry.value))
+	}
+	for i := range 20 {
+		key := fmt.Appendf(nil, "transition-%d", i)
+		value := fmt.Appendf(nil, "value-%d", i)
+		newValue := fmt.Appendf(nil, "new-value-%d", i)
+		// Insert
+		insertTrans, err := trie.InsertWithProof(key, value)
+		if err != nil {
+			t.Fatalf("got unexpected error when inserting key: %s", err)
+		}
+		if insertTrans.NewRoot != trie.Hash() {
+			t.Fatalf("insert transition does not have expected new root")
+		}
+		if !insertTrans.Proof.VerifyInsert(insertTrans.OldRoot, insertTrans.NewRoot, key, value) {
+			t.Fatalf("insert proof did not verify for key %q", key)
+		}
+		if insertTrans.Proof.VerifyInsert(insertTrans.OldRoot, insertTrans.NewRoot, key, newValue) {
+			t.Fatalf("insert proof verified with wrong value for key %q", key)
+		}
+		// Update
+		updateTrans, err := trie.UpdateWithProof(key, newValue)
+		if err != nil {
+			t.Fatalf("got unexpected error when updating key: %s", err)
+		}
+		if updateTrans.OldRoot != insertTrans.NewRoot {
+			t.Fatalf("update transition does not have expected old root")
+		}
+		if !updateTrans.Proof.VerifyUpdate(updateTrans.OldRoot, updateTrans.NewRoot, key, value, newValue) {
+			t.Fatalf("update proof did not verify for key %q", key)
+		}
+		if updateTrans.Proof.VerifyUpdate(updateTrans.OldRoot, updateTrans.NewRoot, key, newValue, value) {
+			t.Fatalf("update proof verified with swapped values for key %q", key)
+		}
+		// Delete
+		deleteTrans, err := trie.DeleteWithProof(key)
+		if err != nil {
+			t.Fatalf("got unexpected error when deleting key: %s", err)
+		}
+		if deleteTrans.NewRoot != insertTrans.OldRoot {
+			t.Fatalf("delete transition does not have expected new root")
+		}
+		if !deleteTrans.Proof.VerifyDelete(deleteTrans.OldRoot, deleteTrans.NewRoot, key, newValue) {
+			t.Fatalf("delete proof did not verify for key %q", key)
+		}
+		if deleteTrans.Proof.VerifyDelete(deleteTrans.NewRoot, deleteTrans.OldRoot, key, newValue) {
+			t.Fatalf("delete proof verified with swapped roots for key %q", key)
+		}
+	}
+}
+
+func TestTrieTransitionErrors(t *testing.T) {
+	trie := NewTrie()
+	trie.Set([]byte("abcd"), []byte("1"))
+	if _, err := trie.InsertWithProof([]byte("abcd"), []byte("2")); err != ErrKeyExists {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyExists)
+	}
+	if _, err := trie.UpdateWithProof([]byte("bcde"), []byte("2")); err != ErrKeyNotExist {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyNotExist)
+	}
+	if _, err := trie.DeleteWithProof([]byte("bcde")); err != ErrKeyNotExist {
+		t.Fatalf("did not get expected error: got %v, expected %v", err, ErrKeyNotExist)
+	}
+}